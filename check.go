@@ -0,0 +1,201 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nelsam/hel/mocks"
+	"golang.org/x/tools/go/packages"
+)
+
+// pendingFile is generated code that is waiting to be written.
+type pendingFile struct {
+	path string
+	src  []byte
+
+	// mocks are the mocks that src was generated from.
+	mocks mocks.Mocks
+}
+
+// checkError is returned when generated code fails to type check.
+type checkError struct {
+	dir      string
+	problems []string
+}
+
+func (e checkError) Error() string {
+	return fmt.Sprintf("generated code in %s does not compile (use --write-invalid to write it anyway):\n  %s",
+		e.dir, strings.Join(e.problems, "\n  "))
+}
+
+// typeCheck type checks files along with the rest of the package in
+// dir (including its tests), without writing them to disk.  The files
+// at the stale paths are about to be removed, so they are checked as if
+// they were empty.  Only errors in the generated files are reported,
+// since the package itself may have errors that hel is not responsible
+// for.
+func typeCheck(dir string, files []pendingFile, stale []string) error {
+	overlay := make(map[string][]byte, len(files)+len(stale))
+	byPath := make(map[string]pendingFile, len(files))
+	for _, path := range stale {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return err
+		}
+		overlay[abs] = []byte("package " + file.Name.Name + "\n")
+	}
+	for _, f := range files {
+		abs, err := filepath.Abs(f.path)
+		if err != nil {
+			return err
+		}
+		overlay[abs] = f.src
+		byPath[abs] = f
+	}
+	pkgs, err := packages.Load(&packages.Config{
+		Mode:    packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:     dir,
+		Tests:   true,
+		Overlay: overlay,
+	}, ".")
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	var problems []string
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, pkgErr := range pkg.Errors {
+			path, line, col := splitPos(pkgErr.Pos)
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				continue
+			}
+			f, ok := byPath[abs]
+			if !ok {
+				continue
+			}
+			problem := fmt.Sprintf("%s:%d:%d: %s", filepath.Base(path), line, col, pkgErr.Msg)
+			if inter := mockAt(f, line); inter != "" {
+				problem += fmt.Sprintf(" (in the mock for %s)", inter)
+			}
+			if seen[problem] {
+				// Test variants of a package report the same errors.
+				continue
+			}
+			seen[problem] = true
+			problems = append(problems, problem)
+		}
+	})
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return checkError{dir: dir, problems: problems}
+}
+
+// splitPos splits a position in the form file:line:col (or
+// file:line).
+func splitPos(pos string) (path string, line, col int) {
+	path = pos
+	var nums []int
+	for len(nums) < 2 {
+		i := strings.LastIndex(path, ":")
+		if i < 0 {
+			break
+		}
+		n, err := strconv.Atoi(path[i+1:])
+		if err != nil {
+			break
+		}
+		nums = append([]int{n}, nums...)
+		path = path[:i]
+	}
+	if len(nums) > 0 {
+		line = nums[0]
+	}
+	if len(nums) > 1 {
+		col = nums[1]
+	}
+	return path, line, col
+}
+
+// mockAt returns the interface whose mock is declared at line in f,
+// or an empty string if line is not part of a mock.
+func mockAt(f pendingFile, line int) string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, f.path, f.src, 0)
+	if err != nil {
+		return ""
+	}
+	for _, decl := range file.Decls {
+		if fset.Position(decl.Pos()).Line > line || fset.Position(decl.End()).Line < line {
+			continue
+		}
+		for _, name := range declTypeNames(decl, fset, line) {
+			for _, mock := range f.mocks {
+				if mock.Name() == name {
+					return mock.Interface()
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// declTypeNames returns the names of the types that may have produced
+// the code at line in decl.
+func declTypeNames(decl ast.Decl, fset *token.FileSet, line int) (names []string) {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return typeNames(d.Recv.List[0].Type)
+		}
+		if d.Type.Results != nil {
+			for _, r := range d.Type.Results.List {
+				names = append(names, typeNames(r.Type)...)
+			}
+		}
+		return names
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			if fset.Position(spec.Pos()).Line > line || fset.Position(spec.End()).Line < line {
+				continue
+			}
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, v := range s.Values {
+					names = append(names, typeNames(v)...)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// typeNames returns the identifiers in expr, which are checked
+// against the names of the mocks.
+func typeNames(expr ast.Expr) (names []string) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			names = append(names, id.Name)
+		}
+		return true
+	})
+	return names
+}
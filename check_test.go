@@ -0,0 +1,90 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/a8m/expect"
+	"github.com/nelsam/hel/mocks"
+)
+
+func TestSplitPos(t *testing.T) {
+	for _, test := range []struct {
+		pos       string
+		path      string
+		line, col int
+	}{
+		{pos: "/foo/helheim_test.go:12:5", path: "/foo/helheim_test.go", line: 12, col: 5},
+		{pos: "/foo/helheim_test.go:12", path: "/foo/helheim_test.go", line: 12},
+		{pos: "/foo/helheim_test.go", path: "/foo/helheim_test.go"},
+		{pos: `C:\foo\helheim_test.go:3:14`, path: `C:\foo\helheim_test.go`, line: 3, col: 14},
+		{pos: "-", path: "-"},
+	} {
+		test := test
+		t.Run(test.pos, func(t *testing.T) {
+			expect := expect.New(t)
+			path, line, col := splitPos(test.pos)
+			expect(path).To.Equal(test.path)
+			expect(line).To.Equal(test.line)
+			expect(col).To.Equal(test.col)
+		})
+	}
+}
+
+// mockAtSrc is generated code for a mock of Foo, followed by a
+// declaration that isn't part of any mock.
+const mockAtSrc = `package foo
+
+var _ Foo = (*mockFoo)(nil)
+
+type mockFoo struct {
+	BarCalled chan bool
+}
+
+func newMockFoo() *mockFoo {
+	return &mockFoo{}
+}
+
+func (m *mockFoo) Bar() {
+	m.BarCalled <- true
+}
+
+var unrelated = 1
+`
+
+func TestMockAt(t *testing.T) {
+	expect := expect.New(t)
+
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package foo\n\ntype Foo interface {\n\tBar()\n}", 0)
+	expect(err).To.Be.Nil().Else.FailNow()
+	mock, err := mocks.For(f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec))
+	expect(err).To.Be.Nil().Else.FailNow()
+	file := pendingFile{path: "helheim_test.go", src: []byte(mockAtSrc), mocks: mocks.Mocks{mock}}
+
+	for _, test := range []struct {
+		name     string
+		line     int
+		expected string
+	}{
+		{name: "package clause", line: 1},
+		{name: "assertion", line: 3, expected: "Foo"},
+		{name: "type", line: 6, expected: "Foo"},
+		{name: "constructor", line: 10, expected: "Foo"},
+		{name: "method", line: 14, expected: "Foo"},
+		{name: "between declarations", line: 16},
+		{name: "unrelated declaration", line: 17},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if actual := mockAt(file, test.line); actual != test.expected {
+				t.Errorf("expected line %d to be in the mock of %q; got %q", test.line, test.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/nelsam/hel/mocks"
+	"github.com/nelsam/hel/packages"
+	"github.com/spf13/cobra"
+)
+
+func cleanCmd() *cobra.Command {
+	clean := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove files generated by hel",
+		Long: "clean removes the files that hel generated in the packages matching the --package flag.  " +
+			"Only files which start with hel's generated code header are removed; files which lack the " +
+			"header are never touched, even if they match the --output name.  Since every file that hel " +
+			"writes has the header, this includes each of the files written with --split-output.  Files which " +
+			"have been edited since hel generated them are skipped unless --force is set.",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 0 {
+				fmt.Print("Invalid usage.  Help:\n\n")
+				cmd.HelpFunc()(cmd, nil)
+				os.Exit(1)
+			}
+			packagePatterns, err := cmd.Flags().GetStringSlice("package")
+			if err != nil {
+				panic(err)
+			}
+			outputName, err := cmd.Flags().GetString("output")
+			if err != nil {
+				panic(err)
+			}
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				panic(err)
+			}
+			force, err := cmd.Flags().GetBool("force")
+			if err != nil {
+				panic(err)
+			}
+			failed := false
+			for _, dir := range packages.Load(packagePatterns...) {
+				if dir.Path() == "" {
+					continue
+				}
+				if err := cleanDir(dir.Path(), outputName, dryRun, force); err != nil {
+					fmt.Printf("Error cleaning %s: %s\n", dir.Path(), err)
+					failed = true
+				}
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+	clean.Flags().BoolP("dry-run", "n", false, "Print the files that would be removed without removing them.")
+	clean.Flags().BoolP("force", "f", false, "Remove generated files even if they have been edited since hel "+
+		"generated them.")
+	return clean
+}
+
+// cleanDir removes all go files in dir which were generated by hel.
+// Files named outputName which were not generated by hel, and files
+// which have been edited since hel generated them (unless force is
+// true), are reported but left alone.
+func cleanDir(dir, outputName string, dryRun, force bool) error {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+	for _, path := range matches {
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		_, generated, err := mocks.ReadHeader(bytes.NewReader(src))
+		if err != nil {
+			return err
+		}
+		if !generated {
+			if filepath.Base(path) == outputName {
+				fmt.Printf("Skipping %s: it was not generated by hel\n", path)
+			}
+			continue
+		}
+		intact, err := mocks.Intact(src)
+		if err != nil {
+			return err
+		}
+		if !intact && !force {
+			fmt.Printf("Skipping %s: it has been edited since hel generated it (use --force to remove it anyway)\n", path)
+			continue
+		}
+		if dryRun {
+			fmt.Printf("Would remove %s\n", path)
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", path)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/a8m/expect"
+)
+
+func TestCleanDir(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		dryRun bool
+		force  bool
+		files  map[string]string
+		remain []string
+	}{
+		{
+			name:   "removes generated files",
+			files:  map[string]string{"helheim_test.go": generatedFile, "mock_foo_test.go": generatedFile, "foo.go": sourceFile},
+			remain: []string{"foo.go"},
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+			files:  map[string]string{"helheim_test.go": generatedFile, "foo.go": sourceFile},
+			remain: []string{"foo.go", "helheim_test.go"},
+		},
+		{
+			name:   "skips non-generated output",
+			files:  map[string]string{"helheim_test.go": sourceFile},
+			remain: []string{"helheim_test.go"},
+		},
+		{
+			name:   "skips edited files",
+			files:  map[string]string{"helheim_test.go": editedFile, "mock_foo_test.go": generatedFile},
+			remain: []string{"helheim_test.go"},
+		},
+		{
+			name:   "force removes edited files",
+			force:  true,
+			files:  map[string]string{"helheim_test.go": editedFile, "mock_foo_test.go": generatedFile},
+			remain: []string{},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			expect := expect.New(t)
+			dir, cleanup := tempDir(t, testFiles(expect, test.files))
+			defer cleanup()
+
+			err := cleanDir(dir, "helheim_test.go", test.dryRun, test.force)
+			expect(err).To.Be.Nil().Else.FailNow()
+			expect(goFiles(expect, dir)).To.Equal(test.remain)
+		})
+	}
+}
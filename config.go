@@ -0,0 +1,229 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nelsam/hel/mocks"
+	"github.com/spf13/cobra"
+)
+
+// configName is the name of the config file that hel looks for in
+// the working directory and its parents.
+const configName = "hel.json"
+
+// config is the contents of a config file.  Each value is used as the
+// default for the command line flag of the same name.
+type config struct {
+	Output      string         `json:"output"`
+	SplitOutput string         `json:"split-output"`
+	HeaderFile  string         `json:"header-file"`
+	Records     bool           `json:"records"`
+	SideEffects bool           `json:"side-effects"`
+	Unbounded   bool           `json:"unbounded"`
+	Verify      bool           `json:"verify"`
+	Lifecycle   bool           `json:"lifecycle"`
+	Context     bool           `json:"context"`
+	Spies       bool           `json:"spies"`
+	Helpers     bool           `json:"helpers"`
+	ChanSizes   map[string]int `json:"chan-sizes"`
+	EmptyOutput string         `json:"empty-output"`
+	Style       string         `json:"style"`
+	Naming      naming         `json:"naming"`
+}
+
+// naming is the naming scheme in a config file.  Each value is used
+// as the default for the --name-* flag of the same name.
+type naming struct {
+	Type          string `json:"type"`
+	Constructor   string `json:"constructor"`
+	Called        string `json:"called"`
+	Input         string `json:"input"`
+	Output        string `json:"output"`
+	SideEffect    string `json:"side-effect"`
+	EmptyOutput   string `json:"empty-output"`
+	Func          string `json:"func"`
+	Calls         string `json:"calls"`
+	Spy           string `json:"spy"`
+	Results       string `json:"results"`
+	Returns       string `json:"returns"`
+	AlwaysReturns string `json:"always-returns"`
+	Args          string `json:"args"`
+	Call          string `json:"call"`
+	Receiver      string `json:"receiver"`
+}
+
+// validate returns an error if any of the templates in n are invalid.
+// The flags that n is applied to are only parsed when generating mocks,
+// so invalid templates would otherwise go unnoticed until then.
+func (n naming) validate() error {
+	return mocks.Naming{
+		Type:          n.Type,
+		Constructor:   n.Constructor,
+		Called:        n.Called,
+		Input:         n.Input,
+		Output:        n.Output,
+		SideEffect:    n.SideEffect,
+		EmptyOutput:   n.EmptyOutput,
+		Func:          n.Func,
+		Calls:         n.Calls,
+		Spy:           n.Spy,
+		Results:       n.Results,
+		Returns:       n.Returns,
+		AlwaysReturns: n.AlwaysReturns,
+		Args:          n.Args,
+		Call:          n.Call,
+		Receiver:      n.Receiver,
+	}.Validate()
+}
+
+// flagValues returns the values in c keyed by flag name.  Relative
+// paths are resolved relative to dir, the directory that c was loaded
+// from.
+func (c config) flagValues(dir string) map[string]string {
+	values := map[string]string{
+		"output":       c.Output,
+		"split-output": c.SplitOutput,
+		"empty-output": c.EmptyOutput,
+		"style":        c.Style,
+
+		"name-type":           c.Naming.Type,
+		"name-constructor":    c.Naming.Constructor,
+		"name-called":         c.Naming.Called,
+		"name-input":          c.Naming.Input,
+		"name-output":         c.Naming.Output,
+		"name-side-effect":    c.Naming.SideEffect,
+		"name-empty-output":   c.Naming.EmptyOutput,
+		"name-func":           c.Naming.Func,
+		"name-calls":          c.Naming.Calls,
+		"name-spy":            c.Naming.Spy,
+		"name-results":        c.Naming.Results,
+		"name-returns":        c.Naming.Returns,
+		"name-always-returns": c.Naming.AlwaysReturns,
+		"name-args":           c.Naming.Args,
+		"name-call":           c.Naming.Call,
+		"name-receiver":       c.Naming.Receiver,
+	}
+	if c.HeaderFile != "" {
+		values["header-file"] = resolve(dir, c.HeaderFile)
+	}
+	if c.Records {
+		values["records"] = "true"
+	}
+	if c.SideEffects {
+		values["side-effects"] = "true"
+	}
+	if c.Unbounded {
+		values["unbounded"] = "true"
+	}
+	if c.Verify {
+		values["verify"] = "true"
+	}
+	if c.Lifecycle {
+		values["lifecycle"] = "true"
+	}
+	if c.Context {
+		values["context"] = "true"
+	}
+	if c.Spies {
+		values["spies"] = "true"
+	}
+	if c.Helpers {
+		values["helpers"] = "true"
+	}
+	if len(c.ChanSizes) > 0 {
+		var sizes []string
+		for name, size := range c.ChanSizes {
+			sizes = append(sizes, fmt.Sprintf("%s=%d", name, size))
+		}
+		// Sort the sizes so that they are applied in the same order
+		// every time.
+		sort.Strings(sizes)
+		values["chan-sizes"] = strings.Join(sizes, ",")
+	}
+	return values
+}
+
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// applyConfig loads the config file for cmd and uses its values for
+// any of cmd's flags which were not set on the command line.
+func applyConfig(cmd *cobra.Command) error {
+	path, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		if path, err = findConfig(); err != nil {
+			return err
+		}
+	}
+	if path == "" {
+		return nil
+	}
+	c, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	for name, value := range c.flagValues(filepath.Dir(path)) {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed || value == "" {
+			continue
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("%s: invalid value for %s: %s", path, name, err)
+		}
+	}
+	return nil
+}
+
+// findConfig looks for a config file in the working directory and
+// each of its parents, returning an empty path if none is found.
+func findConfig() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, configName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+func loadConfig(path string) (config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return config{}, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	var c config
+	if err := dec.Decode(&c); err != nil {
+		return config{}, fmt.Errorf("could not parse config file %s: %s", path, err)
+	}
+	if err := c.Naming.validate(); err != nil {
+		return config{}, fmt.Errorf("invalid naming in config file %s: %s", path, err)
+	}
+	return c, nil
+}
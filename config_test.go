@@ -0,0 +1,141 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/a8m/expect"
+	"github.com/spf13/cobra"
+)
+
+func TestLoadConfig(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		src      string
+		expected config
+		err      bool
+	}{
+		{
+			name:     "valid",
+			src:      `{"output": "mocks_test.go", "header-file": "header.txt"}`,
+			expected: config{Output: "mocks_test.go", HeaderFile: "header.txt"},
+		},
+		{
+			name:     "valid naming",
+			src:      `{"naming": {"type": "fake{{.Interface}}"}}`,
+			expected: config{Naming: naming{Type: "fake{{.Interface}}"}},
+		},
+		{
+			name: "naming with an unknown variable",
+			src:  `{"naming": {"type": "fake{{.Type}}"}}`,
+			err:  true,
+		},
+		{
+			name: "naming with an invalid identifier",
+			src:  `{"naming": {"called": "{{.Method}}-called"}}`,
+			err:  true,
+		},
+		{
+			name: "unknown field",
+			src:  `{"outptu": "mocks_test.go"}`,
+			err:  true,
+		},
+		{
+			name: "invalid json",
+			src:  `{"output": "mocks_test.go"`,
+			err:  true,
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			expect := expect.New(t)
+			dir, cleanup := tempDir(t, map[string][]byte{configName: []byte(test.src)})
+			defer cleanup()
+
+			c, err := loadConfig(filepath.Join(dir, configName))
+			expect(err != nil).To.Equal(test.err)
+			expect(c).To.Equal(test.expected)
+		})
+	}
+}
+
+func TestConfigFlagValues(t *testing.T) {
+	expect := expect.New(t)
+
+	c := config{Output: "mocks_test.go", HeaderFile: "header.txt"}
+	values := c.flagValues("/foo")
+	expect(values["output"]).To.Equal("mocks_test.go")
+	expect(values["header-file"]).To.Equal(filepath.Join("/foo", "header.txt"))
+
+	c.HeaderFile = "/bar/header.txt"
+	expect(c.flagValues("/foo")["header-file"]).To.Equal("/bar/header.txt")
+
+	c.HeaderFile = ""
+	_, ok := c.flagValues("/foo")["header-file"]
+	expect(ok).Not.To.Be.Ok()
+}
+
+func TestApplyConfig(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		src    string
+		args   []string
+		output string
+		header string
+		err    bool
+	}{
+		{
+			name:   "defaults",
+			src:    `{}`,
+			output: "helheim_test.go",
+		},
+		{
+			name:   "config",
+			src:    `{"output": "mocks_test.go", "header-file": "/header.txt"}`,
+			output: "mocks_test.go",
+			header: "/header.txt",
+		},
+		{
+			name:   "flags override config",
+			src:    `{"output": "mocks_test.go", "header-file": "/header.txt"}`,
+			args:   []string{"--output", "fakes_test.go"},
+			output: "fakes_test.go",
+			header: "/header.txt",
+		},
+		{
+			name: "unknown field",
+			src:  `{"output": "mocks_test.go", "ouptut": "fakes_test.go"}`,
+			err:  true,
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			expect := expect.New(t)
+			dir, cleanup := tempDir(t, map[string][]byte{configName: []byte(test.src)})
+			defer cleanup()
+
+			cmd := &cobra.Command{}
+			cmd.Flags().String("config", "", "")
+			cmd.Flags().StringP("output", "o", "helheim_test.go", "")
+			cmd.Flags().String("header-file", "", "")
+			args := append([]string{"--config", filepath.Join(dir, configName)}, test.args...)
+			expect(cmd.Flags().Parse(args)).To.Be.Nil().Else.FailNow()
+
+			err := applyConfig(cmd)
+			expect(err != nil).To.Equal(test.err)
+			if test.err {
+				return
+			}
+			output, err := cmd.Flags().GetString("output")
+			expect(err).To.Be.Nil().Else.FailNow()
+			expect(output).To.Equal(test.output)
+			header, err := cmd.Flags().GetString("header-file")
+			expect(err).To.Be.Nil().Else.FailNow()
+			expect(header).To.Equal(test.header)
+		})
+	}
+}
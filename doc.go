@@ -3,4 +3,8 @@
 // accompanying UNLICENSE file.
 
 // Package main implements the hel command.
+//
+// Runtime style mocks require Go 1.18 or later, since the helrt
+// package that they are built on uses generics.  pers and the other
+// mock styles do not.
 package main
@@ -0,0 +1,79 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/a8m/expect"
+	"github.com/nelsam/hel/mocks"
+)
+
+// generatedSrc returns the source of a file generated by hel, with
+// directives (e.g. "layout foo_test.go") added to its header.
+func generatedSrc(expect func(interface{}) *expect.Expect, directives ...string) []byte {
+	header := "// Code generated by github.com/nelsam/hel. DO NOT EDIT.\n//\n//hel:version (devel)\n"
+	for _, d := range directives {
+		header += "//hel:" + d + "\n"
+	}
+	header += "//hel:types Foo\n//hel:hash 0\n\n"
+	src, err := mocks.Sign([]byte(header + "package foo\n"))
+	expect(err).To.Be.Nil().Else.FailNow()
+	return src
+}
+
+// The kinds of files that testFiles creates.
+const (
+	sourceFile    = "source"
+	generatedFile = "generated"
+	editedFile    = "edited"
+)
+
+// testFiles returns the source of the files in kinds, keyed by name.
+// Each file's kind is sourceFile, generatedFile, or editedFile (a
+// generated file that has been changed by hand).  Generated files have
+// directives added to their headers.
+func testFiles(expect func(interface{}) *expect.Expect, kinds map[string]string, directives ...string) map[string][]byte {
+	files := make(map[string][]byte, len(kinds))
+	for name, kind := range kinds {
+		switch kind {
+		case sourceFile:
+			files[name] = []byte("package foo\n")
+		case generatedFile:
+			files[name] = generatedSrc(expect, directives...)
+		case editedFile:
+			files[name] = append(generatedSrc(expect, directives...), "\nvar edited = true\n"...)
+		}
+	}
+	return files
+}
+
+// tempDir creates a temporary directory containing files, returning
+// its path and a func which removes it.
+func tempDir(t *testing.T, files map[string][]byte) (string, func()) {
+	expect := expect.New(t)
+	dir, err := ioutil.TempDir("", "hel")
+	expect(err).To.Be.Nil().Else.FailNow()
+	for name, src := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), src, 0644)
+		expect(err).To.Be.Nil().Else.FailNow()
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// goFiles returns the names of the go files in dir.
+func goFiles(expect func(interface{}) *expect.Expect, dir string) []string {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	expect(err).To.Be.Nil().Else.FailNow()
+	names := make([]string, 0, len(matches))
+	for _, path := range matches {
+		names = append(names, strings.TrimPrefix(path, dir+string(filepath.Separator)))
+	}
+	return names
+}
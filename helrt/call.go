@@ -0,0 +1,198 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package helrt
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Queue is the untyped view of a Call.  It allows pers to work with
+// runtime style mocks without knowing their argument and result
+// types, or the names of their fields.
+type Queue interface {
+	// Method returns the name of the mocked method that the queue
+	// belongs to.
+	Method() string
+
+	// ReceiveArgs waits up to timeout for a call to the method and
+	// returns its arguments, in order, along with whether a call was
+	// made.  A zero timeout only receives a call that has already been
+	// made.
+	ReceiveArgs(timeout time.Duration) (args []reflect.Value, ok bool)
+
+	// ReturnValues queues one set of results built from values, which
+	// must match the method's results in order.  Nil values are
+	// zero values.
+	ReturnValues(values ...interface{}) error
+
+	// AlwaysReturnValues keeps returning results built from values
+	// from calls which have no other results queued, until the
+	// returned stop func is called.
+	AlwaysReturnValues(values ...interface{}) (stop func(), err error)
+}
+
+// Call queues the calls to a single mocked method, along with the
+// results to return from them.  In is a struct holding the method's
+// arguments and Out is a struct holding its results; either is
+// struct{} if the method has none.
+//
+// A Call must be initialized with Init before it is used.  After that,
+// it only holds channels, so copies of it share the same queues.
+type Call[In, Out any] struct {
+	method  string
+	calls   chan In
+	results chan Out
+
+	// always is unbuffered, so that AlwaysReturn only hands out
+	// results as calls are made.
+	always chan Out
+}
+
+// Init initializes c as the queue for the method named method.  Up to
+// calls calls, and results sets of results, are buffered.
+func (c *Call[In, Out]) Init(method string, calls, results int) {
+	c.method = method
+	c.calls = make(chan In, calls)
+	c.results = make(chan Out, results)
+	c.always = make(chan Out)
+}
+
+// Method returns the name of the method that c belongs to.
+func (c *Call[In, Out]) Method() string {
+	return c.method
+}
+
+// Invoke records a call with the arguments in and waits for its
+// results.  Results queued with Return take precedence over those
+// handed out by AlwaysReturn.  Mocks call it from methods which have
+// results.
+func (c *Call[In, Out]) Invoke(in In) Out {
+	c.calls <- in
+	select {
+	case out := <-c.results:
+		return out
+	default:
+	}
+	select {
+	case out := <-c.results:
+		return out
+	case out := <-c.always:
+		return out
+	}
+}
+
+// Record records a call with the arguments in.  Mocks call it from
+// methods which have no results.
+func (c *Call[In, Out]) Record(in In) {
+	c.calls <- in
+}
+
+// Return queues one set of results.
+func (c *Call[In, Out]) Return(out Out) {
+	c.results <- out
+}
+
+// AlwaysReturn keeps returning out from calls which have no other
+// results queued, until stop is called.  Unlike Return, it does not
+// queue out: it is handed to each call as the call is made, so none is
+// left over after stop is called.
+func (c *Call[In, Out]) AlwaysReturn(out Out) (stop func()) {
+	done, exited := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case c.always <- out:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// Args waits for a call and returns its arguments.
+func (c *Call[In, Out]) Args() In {
+	return <-c.calls
+}
+
+// Calls returns the channel that c's calls are sent on, for tests
+// which need to select on it (e.g. along with a timeout).
+func (c *Call[In, Out]) Calls() <-chan In {
+	return c.calls
+}
+
+// ReceiveArgs implements Queue.
+func (c *Call[In, Out]) ReceiveArgs(timeout time.Duration) ([]reflect.Value, bool) {
+	var in In
+	if timeout == 0 {
+		select {
+		case in = <-c.calls:
+		default:
+			return nil, false
+		}
+	} else {
+		select {
+		case in = <-c.calls:
+		case <-time.After(timeout):
+			return nil, false
+		}
+	}
+	v := reflect.ValueOf(&in).Elem()
+	args := make([]reflect.Value, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		args = append(args, v.Field(i))
+	}
+	return args, true
+}
+
+// ReturnValues implements Queue.
+func (c *Call[In, Out]) ReturnValues(values ...interface{}) error {
+	out, err := c.newResults(values...)
+	if err != nil {
+		return err
+	}
+	c.Return(out)
+	return nil
+}
+
+// AlwaysReturnValues implements Queue.
+func (c *Call[In, Out]) AlwaysReturnValues(values ...interface{}) (func(), error) {
+	out, err := c.newResults(values...)
+	if err != nil {
+		return nil, err
+	}
+	return c.AlwaysReturn(out), nil
+}
+
+// newResults returns the results built from values.
+func (c *Call[In, Out]) newResults(values ...interface{}) (Out, error) {
+	var out Out
+	v := reflect.ValueOf(&out).Elem()
+	if len(values) != v.NumField() {
+		argString := "value"
+		if v.NumField() != 1 {
+			argString = "values"
+		}
+		return out, fmt.Errorf("helrt: expected %d %s for %s; got %d", v.NumField(), argString, c.method, len(values))
+	}
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		field := v.Type().Field(i)
+		valueV := reflect.ValueOf(value)
+		if !valueV.Type().AssignableTo(field.Type) {
+			return out, fmt.Errorf("helrt: value %d (%T) for %s is not assignable to %s", i, value, c.method, field.Type)
+		}
+		v.Field(i).Set(valueV)
+	}
+	return out, nil
+}
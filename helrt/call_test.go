@@ -0,0 +1,103 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package helrt_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nelsam/hel/helrt"
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+	"github.com/poy/onpar/matchers"
+)
+
+type (
+	fooIn struct {
+		X int
+		Y []string
+	}
+	fooOut struct {
+		S   string
+		Err error
+	}
+)
+
+func TestCall(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expect.Expectation {
+		return expect.New(t)
+	})
+
+	newCall := func() *helrt.Call[fooIn, fooOut] {
+		c := &helrt.Call[fooIn, fooOut]{}
+		c.Init("Foo", 10, 10)
+		return c
+	}
+
+	o.Spec("it returns queued results from calls", func(expect expect.Expectation) {
+		c := newCall()
+		c.Return(fooOut{S: "foo"})
+		out := c.Invoke(fooIn{X: 1, Y: []string{"bar"}})
+		expect(out.S).To(matchers.Equal("foo"))
+		expect(c.Args()).To(matchers.Equal(fooIn{X: 1, Y: []string{"bar"}}))
+	})
+
+	o.Spec("it records calls without waiting", func(expect expect.Expectation) {
+		c := newCall()
+		c.Record(fooIn{X: 2})
+		expect(c.Calls()).To(matchers.Receive())
+	})
+
+	o.Spec("it keeps returning results until stopped", func(expect expect.Expectation) {
+		c := newCall()
+		stop := c.AlwaysReturn(fooOut{S: "foo"})
+		for i := 0; i < 50; i++ {
+			expect(c.Invoke(fooIn{X: i}).S).To(matchers.Equal("foo"))
+			expect(c.Args().X).To(matchers.Equal(i))
+		}
+		stop()
+	})
+
+	o.Spec("it leaves no results behind after AlwaysReturn is stopped", func(expect expect.Expectation) {
+		c := newCall()
+		stop := c.AlwaysReturn(fooOut{S: "foo"})
+		c.Return(fooOut{S: "bar"})
+		expect(c.Invoke(fooIn{}).S).To(matchers.Equal("bar"))
+		expect(c.Invoke(fooIn{}).S).To(matchers.Equal("foo"))
+		stop()
+		c.Return(fooOut{S: "baz"})
+		expect(c.Invoke(fooIn{}).S).To(matchers.Equal("baz"))
+	})
+
+	o.Spec("it is a queue", func(expect expect.Expectation) {
+		var q helrt.Queue = newCall()
+		expect(q.Method()).To(matchers.Equal("Foo"))
+
+		_, ok := q.ReceiveArgs(0)
+		expect(ok).To(matchers.BeFalse())
+		_, ok = q.ReceiveArgs(time.Millisecond)
+		expect(ok).To(matchers.BeFalse())
+
+		err := q.ReturnValues("foo", nil)
+		expect(err).To(matchers.Not(matchers.HaveOccurred()))
+		go q.(*helrt.Call[fooIn, fooOut]).Invoke(fooIn{X: 3})
+		args, ok := q.ReceiveArgs(time.Second)
+		expect(ok).To(matchers.BeTrue())
+		expect(len(args)).To(matchers.Equal(2))
+		expect(args[0].Interface()).To(matchers.Equal(3))
+
+		err = q.ReturnValues("foo")
+		expect(err).To(matchers.HaveOccurred())
+		err = q.ReturnValues(1, nil)
+		expect(err).To(matchers.HaveOccurred())
+
+		stop, err := q.AlwaysReturnValues("foo", nil)
+		expect(err).To(matchers.Not(matchers.HaveOccurred()))
+		stop()
+	})
+}
@@ -0,0 +1,17 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+// Package helrt (hel runtime) contains the types that hel's runtime
+// style mocks (generated with --style runtime) are built on.  Instead
+// of declaring and managing their own channels, those mocks hold a
+// Call for each method and are thin typed wrappers around it, which
+// keeps the generated code small.
+//
+// pers works with runtime style mocks through the Queue interface,
+// which every Call implements.
+//
+// helrt uses generics, so it requires Go 1.18 or later.  pers does not
+// import it, so pers (and the other mock styles) still work with older
+// versions of Go.
+package helrt
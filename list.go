@@ -0,0 +1,234 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+
+	"github.com/nelsam/hel/mocks"
+	"github.com/nelsam/hel/packages"
+	"github.com/nelsam/hel/types"
+	"github.com/spf13/cobra"
+)
+
+// listedPackage is a package that hel would generate mocks for.
+type listedPackage struct {
+	Package    string            `json:"package"`
+	Dir        string            `json:"dir"`
+	Output     string            `json:"output,omitempty"`
+	Interfaces []listedInterface `json:"interfaces"`
+
+	// Dependencies are the mocks of the package's dependencies, along
+	// with the files that they would be written to.  They are only
+	// listed when output is split, since they are otherwise written
+	// to Output.
+	Dependencies []listedDependency `json:"dependencies,omitempty"`
+}
+
+// listedDependency is a dependency that hel would generate a mock for
+// along with the interfaces in a package.
+type listedDependency struct {
+	Name   string `json:"name"`
+	Output string `json:"output"`
+}
+
+// listedInterface is an interface type that hel would generate a
+// mock for.
+type listedInterface struct {
+	Name         string   `json:"name"`
+	Output       string   `json:"output,omitempty"`
+	Methods      int      `json:"methods"`
+	Dependencies []string `json:"dependencies"`
+}
+
+func listCmd() *cobra.Command {
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the interface types that hel would generate mocks for",
+		Long: "list loads the packages and types matching the --package and --type flags and prints " +
+			"each interface that hel would mock, grouped by package.  Each interface is shown with " +
+			"its method count and the dependency mocks that hel would generate along with it.  With " +
+			"--split-output, each mock (including each dependency mock) is shown with the file that it " +
+			"would be written to.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return applyConfig(cmd)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 0 {
+				fmt.Print("Invalid usage.  Help:\n\n")
+				cmd.HelpFunc()(cmd, nil)
+				os.Exit(1)
+			}
+			packagePatterns, err := cmd.Flags().GetStringSlice("package")
+			if err != nil {
+				panic(err)
+			}
+			typePatterns, err := cmd.Flags().GetStringSlice("type")
+			if err != nil {
+				panic(err)
+			}
+			outputName, err := cmd.Flags().GetString("output")
+			if err != nil {
+				panic(err)
+			}
+			splitOutput, err := cmd.Flags().GetString("split-output")
+			if err != nil {
+				panic(err)
+			}
+			split, err := splitTemplate(splitOutput)
+			if err != nil {
+				fmt.Printf("Invalid --split-output: %s\n", err)
+				os.Exit(1)
+			}
+			asJSON, err := cmd.Flags().GetBool("json")
+			if err != nil {
+				panic(err)
+			}
+			listed, err := listTypes(loadTypes(packages.Load(packagePatterns...), typePatterns), outputName, split)
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(listed); err != nil {
+					panic(err)
+				}
+				return
+			}
+			for _, pkg := range listed {
+				fmt.Printf("%s (%s)\n", pkg.Package, pkg.Dir)
+				if pkg.Output != "" {
+					fmt.Printf("  output: %s\n", pkg.Output)
+				}
+				for _, inter := range pkg.Interfaces {
+					methods := "methods"
+					if inter.Methods == 1 {
+						methods = "method"
+					}
+					fmt.Printf("  %s: %d %s\n", inter.Name, inter.Methods, methods)
+					if inter.Output != "" {
+						fmt.Printf("    output: %s\n", inter.Output)
+					}
+					if len(inter.Dependencies) > 0 {
+						fmt.Printf("    dependencies: %v\n", inter.Dependencies)
+					}
+				}
+				for _, dep := range pkg.Dependencies {
+					fmt.Printf("  %s (dependency)\n", dep.Name)
+					fmt.Printf("    output: %s\n", dep.Output)
+				}
+			}
+		},
+	}
+	list.Flags().Bool("json", false, "Print the list as JSON, for use in scripts.")
+	return list
+}
+
+// listTypes lists the interfaces in typeDirs.  If split is non-nil,
+// each interface (and each dependency) is listed with the file that
+// its mock would be written to; otherwise, each package is listed with
+// outputName.
+func listTypes(typeDirs types.Dirs, outputName string, split *template.Template) ([]listedPackage, error) {
+	listed := make([]listedPackage, 0, len(typeDirs))
+	for _, dir := range typeDirs {
+		pkg := listedPackage{
+			Package: dir.Package(),
+			Dir:     dir.Dir(),
+		}
+		var outputs map[string]string
+		if split == nil {
+			pkg.Output = filepath.Join(dir.Dir(), outputName)
+		} else {
+			var err error
+			if outputs, err = splitOutputs(dir, split); err != nil {
+				return nil, err
+			}
+		}
+		for _, typ := range dir.ExportedTypes() {
+			inter, ok := typ.Type.(*ast.InterfaceType)
+			if !ok {
+				continue
+			}
+			name := typ.Name.String()
+			pkg.Interfaces = append(pkg.Interfaces, listedInterface{
+				Name:         name,
+				Output:       outputs[name],
+				Methods:      methodCount(inter),
+				Dependencies: dependencyNames(dir.Dependencies(inter)),
+			})
+			delete(outputs, name)
+		}
+		// Whatever is left in outputs is a dependency.
+		for name, output := range outputs {
+			pkg.Dependencies = append(pkg.Dependencies, listedDependency{Name: name, Output: output})
+		}
+		sort.Slice(pkg.Dependencies, func(i, j int) bool {
+			return pkg.Dependencies[i].Name < pkg.Dependencies[j].Name
+		})
+		listed = append(listed, pkg)
+	}
+	return listed, nil
+}
+
+// splitOutputs returns the paths of the files that the mocks in dir
+// (including dependency mocks) would be written to with the split
+// template split, keyed by the interface that each mock implements.
+// The files are named by splitMocks, just as they are when the mocks
+// are written.
+func splitOutputs(dir types.Dir, split *template.Template) (map[string]string, error) {
+	m, err := mocks.Generate(dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := splitMocks(m, dir.Package(), genOptions{split: split})
+	if err != nil {
+		return nil, err
+	}
+	outputs := make(map[string]string, len(m))
+	for _, file := range files {
+		for _, mock := range file.mocks {
+			outputs[mock.Interface()] = filepath.Join(dir.Dir(), file.name)
+		}
+	}
+	return outputs, nil
+}
+
+func methodCount(inter *ast.InterfaceType) (count int) {
+	if inter.Methods == nil {
+		return 0
+	}
+	for _, method := range inter.Methods.List {
+		if _, ok := method.Type.(*ast.FuncType); ok {
+			count++
+		}
+	}
+	return count
+}
+
+func dependencyNames(deps []types.Dependency) []string {
+	names := make([]string, 0, len(deps))
+	seen := make(map[string]bool, len(deps))
+	for _, dep := range deps {
+		name := dep.Type.Name.String()
+		if dep.PkgName != "" {
+			name = dep.PkgName + "." + name
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,177 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/a8m/expect"
+	"github.com/nelsam/hel/types"
+	"golang.org/x/tools/go/packages"
+)
+
+// listSrc is the package that TestListTypes lists.
+const listSrc = `package foo
+
+import "io"
+
+type Foo interface {
+	Foo(Bar) io.Reader
+	Baz(func() Bar) error
+}
+
+type Bar interface {
+	Bar()
+}
+
+type FooBar interface {
+	Bar
+	Close() error
+}
+
+type Empty interface{}
+
+type Struct struct{}
+`
+
+// listIOSrc is the io package that listSrc imports.
+const listIOSrc = `package io
+
+type Reader interface {
+	Read([]byte) (int, error)
+}
+`
+
+// fakeGoDir is a types.GoDir for a package that has already been
+// parsed.
+type fakeGoDir struct {
+	path    string
+	pkg     *packages.Package
+	imports map[string]*packages.Package
+}
+
+func (d fakeGoDir) Path() string {
+	return d.path
+}
+
+func (d fakeGoDir) Package() *packages.Package {
+	return d.pkg
+}
+
+func (d fakeGoDir) Import(path string) (*packages.Package, error) {
+	pkg, ok := d.imports[path]
+	if !ok {
+		return nil, fmt.Errorf("no package %s", path)
+	}
+	return pkg, nil
+}
+
+func parsePkg(expect func(interface{}) *expect.Expect, src string) *packages.Package {
+	f, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+	expect(err).To.Be.Nil().Else.FailNow()
+	return &packages.Package{Name: f.Name.Name, Syntax: []*ast.File{f}}
+}
+
+// listDirs returns the types in listSrc.
+func listDirs(expect func(interface{}) *expect.Expect) types.Dirs {
+	return types.Load(fakeGoDir{
+		path:    "/foo",
+		pkg:     parsePkg(expect, listSrc),
+		imports: map[string]*packages.Package{"io": parsePkg(expect, listIOSrc)},
+	})
+}
+
+// sortInterfaces sorts the interfaces in each of listed by name, since
+// the order of the types in a package is not stable.
+func sortInterfaces(listed []listedPackage) {
+	for _, pkg := range listed {
+		sort.Slice(pkg.Interfaces, func(i, j int) bool {
+			return pkg.Interfaces[i].Name < pkg.Interfaces[j].Name
+		})
+	}
+}
+
+func TestListTypes(t *testing.T) {
+	expect := expect.New(t)
+
+	listed, err := listTypes(listDirs(expect), "helheim_test.go", nil)
+	expect(err).To.Be.Nil().Else.FailNow()
+	sortInterfaces(listed)
+	expect(listed).To.Equal([]listedPackage{{
+		Package: "foo",
+		Dir:     "/foo",
+		Output:  filepath.Join("/foo", "helheim_test.go"),
+		Interfaces: []listedInterface{
+			{Name: "Bar", Methods: 1, Dependencies: []string{}},
+			{Name: "Empty", Methods: 0, Dependencies: []string{}},
+			{Name: "Foo", Methods: 2, Dependencies: []string{"Bar", "io.Reader"}},
+			{Name: "FooBar", Methods: 2, Dependencies: []string{}},
+		},
+	}})
+}
+
+func TestListTypes_Split(t *testing.T) {
+	expect := expect.New(t)
+
+	split, err := parseFileName("mock_{{snake .Interface}}_test.go")
+	expect(err).To.Be.Nil().Else.FailNow()
+	listed, err := listTypes(listDirs(expect), "helheim_test.go", split)
+	expect(err).To.Be.Nil().Else.FailNow()
+	sortInterfaces(listed)
+	expect(listed).To.Equal([]listedPackage{{
+		Package: "foo",
+		Dir:     "/foo",
+		Interfaces: []listedInterface{
+			{Name: "Bar", Output: filepath.Join("/foo", "mock_bar_test.go"), Methods: 1, Dependencies: []string{}},
+			{Name: "Empty", Output: filepath.Join("/foo", "mock_empty_test.go"), Methods: 0, Dependencies: []string{}},
+			{Name: "Foo", Output: filepath.Join("/foo", "mock_foo_test.go"), Methods: 2, Dependencies: []string{"Bar", "io.Reader"}},
+			{Name: "FooBar", Output: filepath.Join("/foo", "mock_foo_bar_test.go"), Methods: 2, Dependencies: []string{}},
+		},
+		Dependencies: []listedDependency{
+			{Name: "io.Reader", Output: filepath.Join("/foo", "mock_reader_test.go")},
+		},
+	}})
+}
+
+func TestMethodCount(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		src      string
+		expected int
+	}{
+		{name: "empty", src: "interface{}"},
+		{name: "methods", src: "interface{ Foo(); Bar() error }", expected: 2},
+		{name: "embedded interfaces are not counted", src: "interface{ io.Reader; Foo() }", expected: 1},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			expect := expect.New(t)
+			expr, err := parser.ParseExpr(test.src)
+			expect(err).To.Be.Nil().Else.FailNow()
+			expect(methodCount(expr.(*ast.InterfaceType))).To.Equal(test.expected)
+		})
+	}
+}
+
+func TestDependencyNames(t *testing.T) {
+	expect := expect.New(t)
+
+	spec := func(name string) *ast.TypeSpec {
+		return &ast.TypeSpec{Name: &ast.Ident{Name: name}, Type: &ast.InterfaceType{}}
+	}
+	deps := []types.Dependency{
+		{Type: spec("Reader"), PkgName: "io", PkgPath: "io"},
+		{Type: spec("Foo")},
+		{Type: spec("Reader"), PkgName: "io", PkgPath: "io"},
+		{Type: spec("Bar")},
+	}
+	expect(dependencyNames(deps)).To.Equal([]string{"Bar", "Foo", "io.Reader"})
+}
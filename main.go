@@ -5,12 +5,15 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/nelsam/hel/mocks"
@@ -24,7 +27,9 @@ var (
 	goimportsPath string
 )
 
-func init() {
+// findGoimports locates goimports, which is only needed when
+// generating mocks, so that other commands can run without it.
+func findGoimports() {
 	output, err := exec.Command("which", "goimports").Output()
 	if err != nil {
 		fmt.Println("Could not locate goimports: ", err.Error())
@@ -33,19 +38,27 @@ func init() {
 		os.Exit(1)
 	}
 	goimportsPath = strings.TrimSpace(string(output))
+}
 
+func init() {
 	cmd = &cobra.Command{
 		Use:   "hel",
 		Short: "A mock generator for Go",
 		Long: "hel is a simple mock generator.  The origin of the name is the Norse goddess, Hel, " +
 			"who guards over the souls of those unworthy to enter Valhalla.  You can probably " +
 			"guess how much I like mocks.",
+		// Only the commands which use the generation flags load the
+		// config file, so that an invalid one doesn't break the others.
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return applyConfig(cmd)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) > 0 {
 				fmt.Print("Invalid usage.  Help:\n\n")
 				cmd.HelpFunc()(nil, nil)
 				os.Exit(1)
 			}
+			findGoimports()
 			packagePatterns, err := cmd.Flags().GetStringSlice("package")
 			if err != nil {
 				panic(err)
@@ -66,6 +79,46 @@ func init() {
 			if err != nil {
 				panic(err)
 			}
+			records, err := cmd.Flags().GetBool("records")
+			if err != nil {
+				panic(err)
+			}
+			sideEffects, err := cmd.Flags().GetBool("side-effects")
+			if err != nil {
+				panic(err)
+			}
+			unbounded, err := cmd.Flags().GetBool("unbounded")
+			if err != nil {
+				panic(err)
+			}
+			verify, err := cmd.Flags().GetBool("verify")
+			if err != nil {
+				panic(err)
+			}
+			lifecycle, err := cmd.Flags().GetBool("lifecycle")
+			if err != nil {
+				panic(err)
+			}
+			context, err := cmd.Flags().GetBool("context")
+			if err != nil {
+				panic(err)
+			}
+			style, err := cmd.Flags().GetString("style")
+			if err != nil {
+				panic(err)
+			}
+			spies, err := cmd.Flags().GetBool("spies")
+			if err != nil {
+				panic(err)
+			}
+			helpers, err := cmd.Flags().GetBool("helpers")
+			if err != nil {
+				panic(err)
+			}
+			emptyOutput, err := cmd.Flags().GetString("empty-output")
+			if err != nil {
+				panic(err)
+			}
 			noTestPkg, err := cmd.Flags().GetBool("no-test-package")
 			if err != nil {
 				panic(err)
@@ -85,65 +138,503 @@ func init() {
 			fmt.Printf("Loading interface types in matching directories")
 			var typeDirs types.Dirs
 			progress(func() {
-				godirs := make([]types.GoDir, 0, len(dirList))
-				for _, dir := range dirList {
-					godirs = append(godirs, dir)
-				}
-				typeDirs = types.Load(godirs...).Filter(typePatterns...)
+				typeDirs = loadTypes(dirList, typePatterns)
 			})
 			fmt.Print("\n\n")
 
-			fmt.Printf("Generating mocks in output file %s", outputName)
+			watch, err := cmd.Flags().GetBool("watch")
+			if err != nil {
+				panic(err)
+			}
+			watchInterval, err := cmd.Flags().GetDuration("watch-interval")
+			if err != nil {
+				panic(err)
+			}
+			force, err := cmd.Flags().GetBool("force")
+			if err != nil {
+				panic(err)
+			}
+			headerFile, err := cmd.Flags().GetString("header-file")
+			if err != nil {
+				panic(err)
+			}
+			var header *template.Template
+			if headerFile != "" {
+				header, err = template.ParseFiles(headerFile)
+				if err != nil {
+					fmt.Printf("Could not load header file: %s\n", err)
+					os.Exit(1)
+				}
+			}
+			splitOutput, err := cmd.Flags().GetString("split-output")
+			if err != nil {
+				panic(err)
+			}
+			split, err := splitTemplate(splitOutput)
+			if err != nil {
+				fmt.Printf("Invalid --split-output: %s\n", err)
+				os.Exit(1)
+			}
+			chanSizeValues, err := cmd.Flags().GetStringSlice("chan-sizes")
+			if err != nil {
+				panic(err)
+			}
+			chanSizes, err := parseChanSizes(chanSizeValues)
+			if err != nil {
+				fmt.Printf("Invalid --chan-sizes: %s\n", err)
+				os.Exit(1)
+			}
+			writeInvalid, err := cmd.Flags().GetBool("write-invalid")
+			if err != nil {
+				panic(err)
+			}
+			naming, err := namingFlags(cmd)
+			if err != nil {
+				panic(err)
+			}
+			opts := genOptions{
+				args:           os.Args[1:],
+				typePatterns:   typePatterns,
+				outputName:     outputName,
+				chanSize:       chanSize,
+				chanSizes:      chanSizes,
+				blockingReturn: blockingReturn,
+				records:        records,
+				sideEffects:    sideEffects,
+				unbounded:      unbounded,
+				verify:         verify,
+				lifecycle:      lifecycle,
+				context:        context,
+				style:          style,
+				spies:          spies,
+				helpers:        helpers,
+				emptyOutput:    emptyOutput,
+				useTestPkg:     !noTestPkg,
+				force:          force,
+				header:         header,
+				split:          split,
+				naming:         naming,
+				writeInvalid:   writeInvalid,
+			}
+
+			if split != nil {
+				fmt.Printf("Generating mocks in output files named by %s", splitOutput)
+			} else {
+				fmt.Printf("Generating mocks in output file %s", outputName)
+			}
+			var errs []error
+			failed := make(map[string]bool)
 			progress(func() {
 				for _, typeDir := range typeDirs {
-					mockPath, err := makeMocks(typeDir, outputName, chanSize, blockingReturn, !noTestPkg)
-					if err != nil {
-						panic(err)
-					}
-					if mockPath != "" {
-						if err = exec.Command(goimportsPath, "-w", mockPath).Run(); err != nil {
-							panic(err)
-						}
+					if _, err := writeMocks(typeDir, opts); err != nil {
+						errs = append(errs, err)
+						failed[typeDir.Dir()] = true
 					}
 				}
 			})
 			fmt.Print("\n")
+			for _, err := range errs {
+				fmt.Printf("Error: %s\n", err)
+			}
+
+			if watch {
+				// Errors are retried while watching, so that they can
+				// be fixed without restarting hel.
+				watchDirs(dirList, failed, opts, watchInterval)
+			}
+			if len(errs) > 0 {
+				os.Exit(1)
+			}
 		},
 	}
-	cmd.Flags().StringSliceP("package", "p", []string{"."}, "The package(s) to generate mocks for.")
-	cmd.Flags().StringSliceP("type", "t", []string{}, "The type(s) to generate mocks for.  If no types "+
+	cmd.PersistentFlags().String("config", "", "The config file to load when generating or listing mocks.  By "+
+		"default, hel looks for "+configName+" in the working directory and its parents.  Each value in the config "+
+		"file is used as the default for the flag of the same name.")
+	cmd.PersistentFlags().StringSliceP("package", "p", []string{"."}, "The package(s) to generate mocks for.")
+	cmd.PersistentFlags().StringSliceP("type", "t", []string{}, "The type(s) to generate mocks for.  If no types "+
 		"are passed in, all exported interface types will be generated.")
-	cmd.Flags().StringP("output", "o", "helheim_test.go", "The file to write generated mocks to.  Since hel does "+
+	cmd.PersistentFlags().StringP("output", "o", "helheim_test.go", "The file to write generated mocks to.  Since hel does "+
 		"not generate exported types, this file will be saved directly in all packages with generated mocks.  "+
 		"Also note that, since the types are not exported, you will want the file to end in '_test.go'.")
+	cmd.PersistentFlags().String("split-output", "", "A template for the names of output files.  When set, each "+
+		"mock (including dependency mocks) is written to its own file, named by executing the template, and "+
+		"--output is ignored.  The template may use {{.Interface}} and {{.Package}} along with the snake, lower, "+
+		"and title functions, e.g. 'mock_{{snake .Interface}}_test.go'.  Mocks whose names are the same are "+
+		"written to the same file.  Files that were generated with the same template and --type patterns, but "+
+		"which hel no longer generates, are removed.")
+	cmd.Flags().String("name-type", mocks.DefaultNaming.Type, "A template for the names of mock types.  It may use "+
+		"{{.Interface}} along with the snake, lower, and title functions.")
+	cmd.Flags().String("name-constructor", mocks.DefaultNaming.Constructor, "A template for the names of mock "+
+		"constructors.  It may use {{.Interface}} and {{.Type}} (the mock type name).")
+	cmd.Flags().String("name-called", mocks.DefaultNaming.Called, "A template for the names of the fields that "+
+		"record method calls.  It may use {{.Method}}.  Fields with non-default names are tagged so that pers "+
+		"can still find them.")
+	cmd.Flags().String("name-input", mocks.DefaultNaming.Input, "A template for the names of the fields that "+
+		"record method arguments.  It may use {{.Method}}.")
+	cmd.Flags().String("name-output", mocks.DefaultNaming.Output, "A template for the names of the fields that "+
+		"hold method return values.  It may use {{.Method}}.")
+	cmd.Flags().String("name-side-effect", mocks.DefaultNaming.SideEffect, "A template for the names of the "+
+		"side effect fields added by --side-effects.  It may use {{.Method}}.")
+	cmd.Flags().String("name-empty-output", mocks.DefaultNaming.EmptyOutput, "A template for the names of the "+
+		"fields that hold methods' empty output policies (see --empty-output).  It may use {{.Method}}.")
+	cmd.Flags().String("name-func", mocks.DefaultNaming.Func, "A template for the names of the func fields of "+
+		"func style mocks (see --style).  It may use {{.Method}}.")
+	cmd.Flags().String("name-calls", mocks.DefaultNaming.Calls, "A template for the names of the methods that "+
+		"return the call history of func style mocks (see --style).  It may use {{.Method}}.")
+	cmd.Flags().String("name-spy", mocks.DefaultNaming.Spy, "A template for the names of spy types (see --spies).  "+
+		"It may use {{.Interface}} along with the snake, lower, and title functions.  Spy constructors are named "+
+		"by --name-constructor.")
+	cmd.Flags().String("name-results", mocks.DefaultNaming.Results, "A template for the names of the fields that "+
+		"spies send method results on (see --spies).  It may use {{.Method}}.")
+	cmd.Flags().String("name-returns", mocks.DefaultNaming.Returns, "A template for the names of the helper "+
+		"methods that queue one set of return values (see --helpers).  It may use {{.Method}}.")
+	cmd.Flags().String("name-always-returns", mocks.DefaultNaming.AlwaysReturns, "A template for the names of "+
+		"the helper methods that keep returning the same values (see --helpers).  It may use {{.Method}}.")
+	cmd.Flags().String("name-args", mocks.DefaultNaming.Args, "A template for the names of the helper methods "+
+		"that receive the arguments of a call (see --helpers).  It may use {{.Method}}.")
+	cmd.Flags().String("name-call", mocks.DefaultNaming.Call, "A template for the names of the call queue fields "+
+		"of runtime style mocks (see --style).  It may use {{.Method}}.")
+	cmd.Flags().String("name-receiver", mocks.DefaultNaming.Receiver, "A template for the receiver name of mock "+
+		"methods.  It may use {{.Interface}}.")
+	cmd.Flags().String("style", mocks.StyleChan, "The style of generated mocks: "+mocks.StyleChan+" (methods send "+
+		"their arguments on channels and receive their return values from channels), "+mocks.StyleFunc+" (methods "+
+		"record their arguments in a call history, e.g. FooCalls(), and delegate to a func field, e.g. FooFunc), or "+
+		mocks.StyleRuntime+" (methods are thin wrappers around a call queue from github.com/nelsam/hel/helrt, e.g. "+
+		"FooCall, which pers also works with; helrt requires Go 1.18 or later).  Func style methods whose func field is not set return zero values if "+
+		"their empty output policy is "+mocks.EmptyOutputZero+", and panic otherwise.  Interfaces may override the "+
+		"style with a //hel:style <style> directive in their doc comments.  Options which only apply to channels "+
+		"(other than --chan-size, --chan-sizes, and --helpers) are ignored for func and runtime style mocks.")
+	cmd.Flags().Bool("spies", false, "Also generate a spy for each interface (e.g. spyFoo, constructed with "+
+		"newSpyFoo(impl)), which forwards each call to a real implementation.  Spies send their calls and arguments "+
+		"on the same channels as mocks, so pers.HaveMethodExecuted works with them, and send the implementation's "+
+		"return values on a results channel (e.g. FooResults).  Unexported interfaces don't get spies when their "+
+		"mocks are generated in an external test package.")
+	cmd.Flags().Bool("helpers", false, "Add typed helper methods to mocks, so that changes to the mocked "+
+		"interfaces break tests at compile time: FooReturns(...) queues one set of return values, "+
+		"FooAlwaysReturns(...) keeps returning the same values until the func that it returns is called, and "+
+		"FooArgs() waits for a call and returns its arguments.  Spies only have Args helpers, and func style mocks "+
+		"have none.")
 	cmd.Flags().IntP("chan-size", "s", 100, "The size of channels used for method calls.")
+	cmd.Flags().StringSlice("chan-sizes", nil, "Overrides of --chan-size for some channels, as a comma separated "+
+		"list of name[:kind]=size, where name is an interface (e.g. Logger), a method (e.g. Logger.Write), or * "+
+		"for all interfaces, and kind is "+mocks.ChanInput+" (called and input channels) or "+mocks.ChanOutput+
+		".  The most specific override applies.  Interfaces and methods may also set their sizes with "+
+		"//hel:chan-size, //hel:input-chan-size, or //hel:output-chan-size <size> directives in their doc comments.")
 	cmd.Flags().BoolP("blocking-return", "b", false, "Always block when returning from mock even if there is no return value.")
+	cmd.Flags().Bool("records", false, "Send each call's arguments, and receive its return values, as a single "+
+		"record struct rather than on a channel per value.  This keeps the values of concurrent calls from being "+
+		"interleaved.")
+	cmd.Flags().Bool("side-effects", false, "Add a func field for each method (e.g. ReadSideEffect) which, if it is "+
+		"set, is called with the method's arguments before the method receives its return values.  This allows "+
+		"mocks to modify their arguments, e.g. to fill the buffer passed to Read.")
+	cmd.Flags().Bool("unbounded", false, "Never block when sending on called and input channels.  Values which do "+
+		"not fit in a channel (see --chan-size) wait in a backlog until the test receives enough values to make "+
+		"room for them, so a mock may be called any number of times without being drained.")
+	cmd.Flags().Bool("verify", false, "Generate constructors which take a testing.TB (e.g. newMockFoo(t)) and "+
+		"register a cleanup that fails the test if any calls were not asserted on, any output was queued but not "+
+		"returned, or any calls are still blocked inside the mock.  Each report names the method and its leftover "+
+		"arguments or output.  The cleanup is registered with t.Cleanup, so tests using these mocks require Go 1.14 "+
+		"or later.")
+	cmd.Flags().Bool("lifecycle", false, "Add Close and Reset methods to mocks.  Close releases calls that are "+
+		"waiting for output, which return zero values, so that they do not leak into later tests.  Reset drains all "+
+		"of a mock's channels so that it may be reused across subtests.  They are named CloseMock and ResetMock if "+
+		"the mocked interface has methods named Close or Reset.")
+	cmd.Flags().Bool("context", false, "Stop waiting for output in methods whose first parameter is a "+
+		"context.Context when the context is done.  They return zero values, with the context's error in their "+
+		"error result.")
+	cmd.Flags().String("empty-output", "", "What mock methods do when they are called and no "+
+		"output has been queued for them: "+mocks.EmptyOutputBlock+" (wait forever, the default), "+mocks.EmptyOutputZero+
+		" (return zero values), "+mocks.EmptyOutputPanic+" (panic with the method name and arguments), or a "+
+		"duration (e.g. 1s) to panic if no output is queued within it.  Interfaces and methods may override this "+
+		"with a //hel:empty-output <policy> directive in their doc comments.  Setting this (even to "+
+		mocks.EmptyOutputBlock+") also lets tests override the policy by setting a method's EmptyOutput field "+
+		"(leaving it empty keeps the generated policy).")
 	cmd.Flags().Bool("no-test-package", false, "Generate mocks in the primary package rather than in {pkg}_test")
+	cmd.Flags().String("header-file", "", "A file containing text (e.g. a license) to write at the top of generated "+
+		"files.  It is parsed as a text/template and may use {{.Year}} and {{.Package}}.")
+	cmd.Flags().BoolP("force", "f", false, "Overwrite output files even if they were not generated by hel or have "+
+		"been edited since hel generated them.")
+	cmd.Flags().Bool("write-invalid", false, "Write generated mocks even if they fail to type check along with "+
+		"their package.  The type errors are still reported.")
+	cmd.Flags().BoolP("watch", "w", false, "After generating, keep watching the matched packages and regenerate "+
+		"mocks for any package whose (non-generated) go files change.")
+	cmd.Flags().Duration("watch-interval", time.Second, "How often to check for changes when --watch is set.")
+
+	cmd.AddCommand(listCmd(), cleanCmd(), versionCmd())
 }
 
-func makeMocks(types types.Dir, fileName string, chanSize int, blockingReturn, useTestPkg bool) (filePath string, err error) {
-	mocks, err := mocks.Generate(types)
-	if err != nil {
-		return "", err
+// loadTypes loads the interface types in dirs which match any of
+// typePatterns.
+func loadTypes(dirs []packages.Dir, typePatterns []string) types.Dirs {
+	godirs := make([]types.GoDir, 0, len(dirs))
+	for _, dir := range dirs {
+		godirs = append(godirs, dir)
+	}
+	return types.Load(godirs...).Filter(typePatterns...)
+}
+
+// namingFlags returns the naming scheme set by cmd's --name-* flags.
+func namingFlags(cmd *cobra.Command) (mocks.Naming, error) {
+	var n mocks.Naming
+	for _, f := range []struct {
+		flag string
+		dest *string
+	}{
+		{"name-type", &n.Type},
+		{"name-constructor", &n.Constructor},
+		{"name-called", &n.Called},
+		{"name-input", &n.Input},
+		{"name-output", &n.Output},
+		{"name-side-effect", &n.SideEffect},
+		{"name-empty-output", &n.EmptyOutput},
+		{"name-func", &n.Func},
+		{"name-calls", &n.Calls},
+		{"name-spy", &n.Spy},
+		{"name-results", &n.Results},
+		{"name-returns", &n.Returns},
+		{"name-always-returns", &n.AlwaysReturns},
+		{"name-args", &n.Args},
+		{"name-call", &n.Call},
+		{"name-receiver", &n.Receiver},
+	} {
+		v, err := cmd.Flags().GetString(f.flag)
+		if err != nil {
+			return mocks.Naming{}, err
+		}
+		*f.dest = v
 	}
-	if len(mocks) == 0 {
-		return "", nil
+	return n, nil
+}
+
+// parseChanSizes parses the values of the --chan-sizes flag.
+func parseChanSizes(values []string) ([]mocks.ChanSize, error) {
+	var sizes []mocks.ChanSize
+	for _, v := range values {
+		size, err := mocks.ParseChanSize(v)
+		if err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, size)
 	}
-	mocks.SetBlockingReturn(blockingReturn)
-	if useTestPkg {
-		mocks.PrependLocalPackage(types.Package())
+	return sizes, nil
+}
+
+// splitTemplate parses the value of the --split-output flag,
+// returning nil if it is not set.
+func splitTemplate(split string) (*template.Template, error) {
+	if split == "" {
+		return nil, nil
 	}
-	filePath = filepath.Join(types.Dir(), fileName)
-	f, err := os.Create(filePath)
+	return parseFileName(split)
+}
+
+// genOptions are the options used to generate mocks for a package.
+type genOptions struct {
+	args           []string
+	typePatterns   []string
+	outputName     string
+	chanSize       int
+	chanSizes      []mocks.ChanSize
+	blockingReturn bool
+	records        bool
+	sideEffects    bool
+	unbounded      bool
+	verify         bool
+	lifecycle      bool
+	context        bool
+	style          string
+	spies          bool
+	helpers        bool
+	emptyOutput    string
+	useTestPkg     bool
+	force          bool
+	header         *template.Template
+	split          *template.Template
+	naming         mocks.Naming
+	writeInvalid   bool
+}
+
+// headerData is the data that header files are executed with.
+type headerData struct {
+	// Year is the current year.
+	Year int
+
+	// Package is the name of the package that mocks are being
+	// generated for.
+	Package string
+}
+
+// writeMocks generates mocks for types, runs goimports against them,
+// type checks them, and writes the result to the output files.  It returns the paths
+// that were written to or removed, which will be empty if nothing
+// changed.
+func writeMocks(types types.Dir, opts genOptions) (paths []string, err error) {
+	m, err := mocks.Generate(types)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	m.SetBlockingReturn(opts.blockingReturn)
+	m.SetRecords(opts.records)
+	m.SetSideEffects(opts.sideEffects)
+	m.SetUnbounded(opts.unbounded)
+	m.SetVerify(opts.verify)
+	m.SetLifecycle(opts.lifecycle)
+	m.SetContext(opts.context)
+	m.SetHelpers(opts.helpers)
+	m.SetChanSizes(opts.chanSizes)
+	if opts.emptyOutput != "" {
+		if err := m.SetEmptyOutput(opts.emptyOutput); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.SetStyle(opts.style); err != nil {
+		return nil, err
+	}
+	if err := m.SetNaming(opts.naming); err != nil {
+		return nil, err
+	}
+	m.SetSpies(opts.spies)
+	if opts.useTestPkg {
+		m.PrependLocalPackage(types.Package())
 	}
-	defer f.Close()
 	testPkg := types.Package()
-	if useTestPkg {
+	if opts.useTestPkg {
 		testPkg += "_test"
 	}
-	return filePath, mocks.Output(testPkg, types.Dir(), chanSize, f)
+	outputOpts := []mocks.OutputOption{mocks.WithArgs(opts.args...)}
+	if opts.header != nil {
+		var header bytes.Buffer
+		data := headerData{Year: time.Now().Year(), Package: types.Package()}
+		if err := opts.header.Execute(&header, data); err != nil {
+			return nil, err
+		}
+		outputOpts = append(outputOpts, mocks.WithLicense(header.String()))
+	}
+	var layout []string
+	if opts.split != nil {
+		layout = outputLayout(opts)
+		outputOpts = append(outputOpts, mocks.WithLayout(layout...))
+	}
+	files, err := splitMocks(m, types.Package(), opts)
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool, len(files))
+	var pending []pendingFile
+	for _, file := range files {
+		keep[file.name] = true
+		filePath := filepath.Join(types.Dir(), file.name)
+		src, changed, err := renderFile(filePath, file.mocks, testPkg, types.Dir(), opts, outputOpts...)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			pending = append(pending, pendingFile{path: filePath, src: src, mocks: file.mocks})
+		}
+	}
+	var stale []string
+	if opts.split != nil {
+		if stale, err = staleFiles(types.Dir(), layout, keep, opts.force); err != nil {
+			return nil, err
+		}
+	}
+	if len(pending) > 0 {
+		if err := typeCheck(types.Dir(), pending, stale); err != nil {
+			if _, ok := err.(checkError); !ok || !opts.writeInvalid {
+				return nil, err
+			}
+			fmt.Printf("\nWarning: writing mocks anyway: %s\n", err)
+		}
+	}
+	for _, file := range pending {
+		if err := ioutil.WriteFile(file.path, file.src, 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, file.path)
+	}
+	// Stale files are only removed once the new files are in place, so
+	// a failed run leaves the old mocks alone.
+	removed, err := pruneStale(stale)
+	return append(paths, removed...), err
+}
+
+// renderFile renders m as the source for filePath.  changed will be
+// false if the file is already up to date, which is checked against
+// the hash of m's inputs before anything is rendered.  The package has
+// already been loaded by then, so an up to date file only saves
+// rendering, goimports, and type checking.
+func renderFile(filePath string, m mocks.Mocks, pkg, dir string, opts genOptions, outputOpts ...mocks.OutputOption) (src []byte, changed bool, err error) {
+	hash, err := m.Hash(pkg, dir, opts.chanSize, outputOpts...)
+	if err != nil {
+		return nil, false, err
+	}
+	unchanged, err := checkTarget(filePath, hash, opts.force)
+	if err != nil {
+		return nil, false, err
+	}
+	if unchanged {
+		return nil, false, nil
+	}
+	var buf bytes.Buffer
+	if err := m.Output(pkg, dir, opts.chanSize, &buf, outputOpts...); err != nil {
+		return nil, false, err
+	}
+	src, err = goimports(dir, buf.Bytes())
+	if err != nil {
+		return nil, false, err
+	}
+	if src, err = mocks.Sign(src); err != nil {
+		return nil, false, err
+	}
+	return src, true, nil
+}
+
+// checkTarget checks the file at path before it is overwritten.  It
+// returns an error if the file was not generated by hel or has been
+// edited since it was generated, unless force is true.  unchanged
+// will be true if the file was generated from inputs matching hash.
+func checkTarget(path, hash string, force bool) (unchanged bool, err error) {
+	src, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	h, generated, err := mocks.ReadHeader(bytes.NewReader(src))
+	if err != nil {
+		return false, err
+	}
+	intact, err := mocks.Intact(src)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case generated && intact:
+		return h.Hash == hash, nil
+	case force:
+		return false, nil
+	case !generated:
+		return false, fmt.Errorf("refusing to overwrite %s: it was not generated by hel (use --force to overwrite it anyway)", path)
+	default:
+		return false, fmt.Errorf("refusing to overwrite %s: it has been edited since hel generated it (use --force to overwrite it anyway)", path)
+	}
+}
+
+// goimports runs goimports against src as if it were saved in dir.
+func goimports(dir string, src []byte) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(goimportsPath, "-srcdir", dir)
+	cmd.Stdin = bytes.NewReader(src)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("goimports failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
 }
 
 func progress(f func()) {
@@ -0,0 +1,172 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"fmt"
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+// The kinds of channels whose sizes may be set separately.  A
+// method's called channel is sent on along with its input channels,
+// so it is sized with them.
+const (
+	// ChanInput is the kind of a method's called and input channels.
+	ChanInput = "input"
+
+	// ChanOutput is the kind of a method's output channels.
+	ChanOutput = "output"
+)
+
+// chanSizeDirective is the name of the directive that sets the size
+// of all of an interface's or method's channels.  The size of a single
+// kind of channel is set by prefixing it with the kind, e.g.
+// //hel:output-chan-size 0.
+const chanSizeDirective = "chan-size"
+
+// ChanSize overrides the buffer size of some of the channels of the
+// mocks that are generated.
+type ChanSize struct {
+	// Interface is the name of the interface whose mock's channels are
+	// resized.  If it is empty, the channels of all mocks are resized.
+	Interface string
+
+	// Method is the name of the method whose channels are resized.  If
+	// it is empty, the channels of all of Interface's methods are
+	// resized.
+	Method string
+
+	// Kind is the kind of the channels that are resized (ChanInput or
+	// ChanOutput).  If it is empty, both kinds are resized.
+	Kind string
+
+	// Size is the buffer size of the channels.
+	Size int
+}
+
+// ParseChanSize parses a ChanSize from s, which is of the form
+// name[:kind]=size, where name is an interface name optionally
+// followed by a method name (e.g. Logger.Write), or * for all
+// interfaces.
+func ParseChanSize(s string) (ChanSize, error) {
+	eq := strings.LastIndex(s, "=")
+	if eq < 0 {
+		return ChanSize{}, fmt.Errorf("invalid chan size %q: expected name[:kind]=size", s)
+	}
+	size, err := parseChanSize(s[eq+1:])
+	if err != nil {
+		return ChanSize{}, fmt.Errorf("invalid chan size %q: %s", s, err)
+	}
+	c := ChanSize{Size: size}
+	name := s[:eq]
+	if colon := strings.Index(name, ":"); colon >= 0 {
+		name, c.Kind = name[:colon], name[colon+1:]
+		if err := checkChanKind(c.Kind); err != nil {
+			return ChanSize{}, fmt.Errorf("invalid chan size %q: %s", s, err)
+		}
+	}
+	if name == "*" {
+		return c, nil
+	}
+	c.Interface = name
+	if dot := strings.Index(name, "."); dot >= 0 {
+		c.Interface, c.Method = name[:dot], name[dot+1:]
+	}
+	if c.Interface == "" || c.Method == "" && strings.Contains(name, ".") {
+		return ChanSize{}, fmt.Errorf("invalid chan size %q: expected an interface name, optionally followed by a method name, or *", s)
+	}
+	return c, nil
+}
+
+// checkChanKind returns an error if kind is not a kind of channel.
+func checkChanKind(kind string) error {
+	switch kind {
+	case ChanInput, ChanOutput:
+		return nil
+	}
+	return fmt.Errorf("invalid channel kind %q: must be %s or %s", kind, ChanInput, ChanOutput)
+}
+
+// parseChanSize parses the size of a channel from s.
+func parseChanSize(s string) (int, error) {
+	size, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid chan size %q: must be a non-negative integer", s)
+	}
+	return size, nil
+}
+
+// chanSizeDirectives returns the names of the directives that may set
+// the size of a kind of channel, in order of precedence.
+func chanSizeDirectives(kind string) []string {
+	return []string{kind + "-" + chanSizeDirective, chanSizeDirective}
+}
+
+// checkChanSizes returns an error if any of the chan size directives
+// in g are invalid.
+func checkChanSizes(g *ast.CommentGroup) error {
+	for _, name := range []string{chanSizeDirective, ChanInput + "-" + chanSizeDirective, ChanOutput + "-" + chanSizeDirective} {
+		if value, ok := directive(g, name); ok {
+			if _, err := parseChanSize(value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// chanSize returns the size of m's channels of the given kind, which
+// is set (in order of precedence) by m's directives, its interface's
+// directives, its mock's chan sizes, or def.
+func (m Method) chanSize(kind string, def int) int {
+	for _, doc := range []*ast.CommentGroup{m.doc, m.receiver.interfaceDoc} {
+		for _, name := range chanSizeDirectives(kind) {
+			if value, ok := directive(doc, name); ok {
+				// Directives are checked when the mock is created.
+				size, _ := parseChanSize(value)
+				return size
+			}
+		}
+	}
+	size, best := def, -1
+	for _, c := range m.receiver.settings.chanSizes {
+		rank := m.chanSizeRank(c, kind)
+		if rank >= 0 && rank >= best {
+			size, best = c.Size, rank
+		}
+	}
+	return size
+}
+
+// chanSizeRank returns how specific c is to m's channels of the given
+// kind, or -1 if it does not apply to them.  Later chan sizes take
+// precedence over earlier ones of the same rank.
+func (m Method) chanSizeRank(c ChanSize, kind string) int {
+	rank := 0
+	switch c.Interface {
+	case "":
+	case m.receiver.BaseName(), m.receiver.typeName:
+		rank += 4
+	default:
+		return -1
+	}
+	switch c.Method {
+	case "":
+	case m.name:
+		rank += 2
+	default:
+		return -1
+	}
+	switch c.Kind {
+	case "":
+	case kind:
+		rank++
+	default:
+		return -1
+	}
+	return rank
+}
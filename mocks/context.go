@@ -0,0 +1,69 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// isContext returns whether typ is context.Context.
+func isContext(typ ast.Expr) bool {
+	sel, ok := typ.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "context" && sel.Sel.Name == "Context"
+}
+
+// contextParam returns the name of m's context.Context parameter, if
+// m is context aware: its mock is generated with context aware methods,
+// m has outputs to wait for, and its first parameter is a
+// context.Context.  Otherwise, it returns an empty string.
+func (m Method) contextParam(sig signature) string {
+	if !m.receiver.settings.context || !m.hasOutputs() || len(sig.params) == 0 {
+		return ""
+	}
+	if !isContext(sig.params[0].Type) {
+		return ""
+	}
+	return sig.params[0].Names[0].Name
+}
+
+// errorResult returns the index (in sig.zeros) of m's last error
+// result, or -1 if it has none.
+func (m Method) errorResult(sig signature) int {
+	idx, i := -1, 0
+	for _, result := range sig.results {
+		n := len(result.Names)
+		if n == 0 {
+			n = 1
+		}
+		if ident, ok := result.Type.(*ast.Ident); ok && ident.Name == "error" {
+			idx = i + n - 1
+		}
+		i += n
+	}
+	return idx
+}
+
+// contextCase returns a select case which is chosen when ctx is done,
+// setting m's error result (if it has one) to ctx.Err().  The zero
+// values of m's results must be declared before the select.
+func (m Method) contextCase(sig signature, ctx string) *ast.CommClause {
+	clause := &ast.CommClause{Comm: &ast.ExprStmt{X: &ast.UnaryExpr{
+		Op: token.ARROW,
+		X:  &ast.CallExpr{Fun: selectors(ctx, "Done")},
+	}}}
+	if i := m.errorResult(sig); i >= 0 {
+		clause.Body = []ast.Stmt{&ast.AssignStmt{
+			Lhs: []ast.Expr{&ast.Ident{Name: sig.zeros[i]}},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{&ast.CallExpr{Fun: selectors(ctx, "Err")}},
+		}}
+	}
+	return clause
+}
@@ -0,0 +1,62 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// directive returns the value of the hel directive called name in g,
+// and whether g contains that directive.  Directives in the doc
+// comments of interfaces and their methods (e.g. //hel:empty-output
+// zero) configure their mocks.  Like other directives, they are not
+// part of the doc comment's text, so they are not copied to mocks.
+func directive(g *ast.CommentGroup, name string) (value string, ok bool) {
+	if g == nil {
+		return "", false
+	}
+	prefix := directivePrefix + name
+	for _, c := range g.List {
+		if c.Text == prefix {
+			return "", true
+		}
+		if strings.HasPrefix(c.Text, prefix+" ") {
+			return strings.TrimSpace(strings.TrimPrefix(c.Text, prefix)), true
+		}
+	}
+	return "", false
+}
+
+// checkDirectives returns an error if any of the directives on m's
+// interface or methods are invalid.
+func (m Mock) checkDirectives() error {
+	if err := checkDocDirectives(m.interfaceDoc); err != nil {
+		return fmt.Errorf("%s: %s", m.typeName, err)
+	}
+	if style, ok := directive(m.interfaceDoc, styleDirective); ok {
+		if err := checkStyle(style); err != nil {
+			return fmt.Errorf("%s: %s", m.typeName, err)
+		}
+	}
+	for _, method := range m.Methods() {
+		if err := checkDocDirectives(method.doc); err != nil {
+			return fmt.Errorf("%s.%s: %s", m.typeName, method.name, err)
+		}
+	}
+	return nil
+}
+
+// checkDocDirectives returns an error if any of the directives in g
+// are invalid.
+func checkDocDirectives(g *ast.CommentGroup) error {
+	if policy, ok := directive(g, emptyOutputDirective); ok {
+		if err := checkEmptyOutput(policy); err != nil {
+			return err
+		}
+	}
+	return checkChanSizes(g)
+}
@@ -0,0 +1,258 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// docText returns the text of g, without any deprecation notice.
+// Mocks of deprecated types are not themselves deprecated, and
+// copying the notice would cause linters to complain about every use
+// of the mock.
+func docText(g *ast.CommentGroup) string {
+	if g == nil {
+		return ""
+	}
+	paragraphs := strings.Split(strings.TrimSpace(g.Text()), "\n\n")
+	kept := paragraphs[:0]
+	for _, p := range paragraphs {
+		if strings.HasPrefix(p, "Deprecated: ") || p == "" {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	return strings.Join(kept, "\n\n")
+}
+
+// copiedDoc returns the text of g (see docText) to copy onto m's
+// generated code.  Only the first paragraph of a dependency's docs is
+// copied, since they describe the dependency to users of its own
+// package (and are often long, e.g. context.Context's).
+func (m Mock) copiedDoc(g *ast.CommentGroup) string {
+	text := docText(g)
+	if m.dependency {
+		text = strings.SplitN(text, "\n\n", 2)[0]
+	}
+	return text
+}
+
+// commentGroup returns a comment group containing each of the
+// paragraphs in text.
+func commentGroup(paragraphs ...string) *ast.CommentGroup {
+	var g ast.CommentGroup
+	for _, p := range paragraphs {
+		if p == "" {
+			continue
+		}
+		if len(g.List) > 0 {
+			g.List = append(g.List, &ast.Comment{Text: "//"})
+		}
+		for _, line := range strings.Split(strings.TrimRight(commentLines(p), "\n"), "\n") {
+			g.List = append(g.List, &ast.Comment{Text: line})
+		}
+	}
+	if len(g.List) == 0 {
+		return nil
+	}
+	return &g
+}
+
+// docWidth is the width that generated doc comments are wrapped to.
+const docWidth = 70
+
+// wrap wraps text so that its lines are no longer than width, unless
+// a single word is longer.
+func wrap(text string, width int) string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// doc returns the doc comment for m's type, which includes the doc
+// comment of the mocked interface (see copiedDoc).
+func (m Mock) doc() *ast.CommentGroup {
+	summary := fmt.Sprintf("%s is a mock implementation of %s.", m.Name(), m.Interface())
+	if m.settings.spy {
+		summary = fmt.Sprintf("%s is a spy on an implementation of %s.", m.Name(), m.Interface())
+	}
+	return commentGroup(
+		summary,
+		m.copiedDoc(m.interfaceDoc),
+		wrap(m.usage(), docWidth),
+	)
+}
+
+// usage describes how m's channels are used, naming m's fields as
+// examples.
+func (m Mock) usage() string {
+	if m.funcStyle() {
+		return m.funcUsage()
+	}
+	if m.runtimeStyle() {
+		return m.runtimeUsage()
+	}
+	var called, input, output, sideEffect, emptyOutput, returns, alwaysReturns, args string
+	for _, method := range m.Methods() {
+		names := method.fieldNames()
+		if called == "" {
+			called = names.called
+		}
+		if input == "" {
+			input = names.input
+		}
+		if output == "" {
+			output = names.output
+		}
+		if sideEffect == "" {
+			sideEffect = names.sideEffect
+		}
+		if emptyOutput == "" {
+			emptyOutput = names.emptyOutput
+		}
+		if returns == "" {
+			returns, alwaysReturns = names.returns, names.alwaysReturns
+		}
+		if args == "" {
+			args = names.args
+		}
+	}
+	if called == "" {
+		return ""
+	}
+	usage := fmt.Sprintf("Calling a method sends true on its called channel (e.g. %s)", called)
+	switch {
+	case m.settings.spy:
+		if input != "" {
+			usage += fmt.Sprintf(" and its arguments on its input channels (e.g. %s)", input)
+		}
+		usage += fmt.Sprintf(", then forwards the call to the implementation passed to %s", m.ConstructorName())
+		if output != "" {
+			usage += fmt.Sprintf(" and sends the values it returns on its results channels (e.g. %s) before returning them", output)
+		}
+	case m.settings.records:
+		if input != "" {
+			usage += fmt.Sprintf(" and a record of its arguments on its input channel (e.g. %s)", input)
+		}
+		if output != "" {
+			usage += fmt.Sprintf(", then returns the values in the record received from its output channel (e.g. %s)", output)
+		}
+	default:
+		if input != "" {
+			usage += fmt.Sprintf(" and each of its arguments on its input struct's channels (e.g. %s)", input)
+		}
+		if output != "" {
+			usage += fmt.Sprintf(", then returns the values received from its output struct's channels (e.g. %s)", output)
+		}
+	}
+	usage += "."
+	if emptyOutput != "" {
+		usage += fmt.Sprintf(" If a method is called when no output is queued, it follows its empty output policy: %q waits, %q returns zero values, %q panics, and a duration (e.g. \"1s\") panics if no output is queued within it. Setting a method's policy field (e.g. %s) overrides the policy that it was generated with.", EmptyOutputBlock, EmptyOutputZero, EmptyOutputPanic, emptyOutput)
+	}
+	if m.settings.unbounded {
+		usage += " Sending never blocks: values which do not fit in a channel wait in a backlog until there is room for them."
+	}
+	for _, method := range m.Methods() {
+		if method.contextParam(method.signature()) != "" {
+			usage += " If a method's context is done before its output is received, it returns zero values and the context's error."
+			break
+		}
+	}
+	if m.settings.lifecycle {
+		fields := m.fields()
+		usage += fmt.Sprintf(" %s releases calls that are waiting for output, and %s drains all of the channels.", fields.closeMethod, fields.reset)
+	}
+	if m.settings.verify {
+		usage += fmt.Sprintf(" %s registers a cleanup with the test which reports calls that were not asserted on, output that was not returned, and calls that are still blocked (using t.Cleanup, which requires Go 1.14 or later).", m.ConstructorName())
+	}
+	usage += helpersUsage(returns, alwaysReturns, args)
+	if sideEffect != "" {
+		usage += fmt.Sprintf(" If its side effect (e.g. %s) is set, it is called with the method's arguments before the return values are received.", sideEffect)
+	}
+	return usage
+}
+
+// funcUsage describes how the fields and methods of m, which is a
+// func style mock, are used.
+func (m Mock) funcUsage() string {
+	methods := m.Methods()
+	if len(methods) == 0 {
+		return ""
+	}
+	names := methods[0].fieldNames()
+	usage := fmt.Sprintf("Calling a method records its arguments in its call history (e.g. %s()) and then calls its func field (e.g. %s).", names.calls, names.fn)
+	var zero, panics bool
+	for _, method := range methods {
+		if method.emptyOutput() == EmptyOutputZero {
+			zero = true
+		} else {
+			panics = true
+		}
+	}
+	switch {
+	case zero && panics:
+		usage += " If the func field is not set, the method returns zero values or panics, depending on its empty output policy."
+	case zero:
+		usage += " If the func field is not set, the method returns zero values."
+	default:
+		usage += " If the func field is not set, the method panics."
+	}
+	return usage
+}
+
+// runtimeUsage describes how the call queues of m, which is a runtime
+// style mock, are used.
+func (m Mock) runtimeUsage() string {
+	methods := m.Methods()
+	if len(methods) == 0 {
+		return ""
+	}
+	names := methods[0].fieldNames()
+	usage := fmt.Sprintf("Calling a method sends its arguments to its call queue (e.g. %s) and, if it has results, "+
+		"returns the next results queued with the call queue's Return or AlwaysReturn methods. pers works with "+
+		"the call queues as it does with channels.", names.call)
+	var returns, alwaysReturns, args string
+	for _, method := range methods {
+		names := method.fieldNames()
+		if returns == "" {
+			returns, alwaysReturns = names.returns, names.alwaysReturns
+		}
+		if args == "" {
+			args = names.args
+		}
+	}
+	usage += helpersUsage(returns, alwaysReturns, args)
+	return usage
+}
+
+// helpersUsage describes a mock's typed helpers, using the passed in
+// names of the helpers as examples.  Empty names are helpers that the
+// mock does not have.
+func helpersUsage(returns, alwaysReturns, args string) string {
+	switch {
+	case returns != "" && args != "":
+		return fmt.Sprintf(" Typed helpers queue return values (e.g. %s and %s) and wait for calls and return their arguments (e.g. %s).", returns, alwaysReturns, args)
+	case returns != "":
+		return fmt.Sprintf(" Typed helpers queue return values (e.g. %s and %s).", returns, alwaysReturns)
+	case args != "":
+		return fmt.Sprintf(" Typed helpers wait for calls and return their arguments (e.g. %s).", args)
+	}
+	return ""
+}
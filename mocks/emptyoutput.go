@@ -0,0 +1,198 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// The empty output policies decide what a mock's method does when it
+// is called and no output has been queued for it.  A policy may also
+// be a duration (e.g. "1s"), in which case the method waits that long
+// for output before panicking.
+const (
+	// EmptyOutputBlock waits for output forever.
+	EmptyOutputBlock = "block"
+
+	// EmptyOutputZero returns zero values.
+	EmptyOutputZero = "zero"
+
+	// EmptyOutputPanic panics with the method's name and arguments.
+	EmptyOutputPanic = "panic"
+)
+
+// emptyOutputDirective is the name of the directive that sets the
+// empty output policy of an interface or method.
+const emptyOutputDirective = "empty-output"
+
+// checkEmptyOutput returns an error if policy is not a valid empty
+// output policy.
+func checkEmptyOutput(policy string) error {
+	switch policy {
+	case EmptyOutputBlock, EmptyOutputZero, EmptyOutputPanic:
+		return nil
+	}
+	d, err := time.ParseDuration(policy)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid empty output policy %q: must be %s, %s, %s, or a positive duration (e.g. 1s)",
+			policy, EmptyOutputBlock, EmptyOutputZero, EmptyOutputPanic)
+	}
+	return nil
+}
+
+// emptyOutput returns m's empty output policy, which is set (in order
+// of precedence) by m's directive, its interface's directive, or its
+// mock's settings.
+func (m Method) emptyOutput() string {
+	if policy, ok := directive(m.doc, emptyOutputDirective); ok {
+		return policy
+	}
+	if policy, ok := directive(m.receiver.interfaceDoc, emptyOutputDirective); ok {
+		return policy
+	}
+	if m.receiver.settings.emptyOutput != "" {
+		return m.receiver.settings.emptyOutput
+	}
+	return EmptyOutputBlock
+}
+
+// guarded returns whether m needs to check for queued output before
+// waiting for it, i.e. it has outputs and either does not simply block
+// or its mock was generated with an explicit policy (e.g. --empty-output
+// block), which opts in to overriding the policy at runtime.  Spies do
+// not wait for output.
+func (m Method) guarded() bool {
+	if m.receiver.settings.spy || !m.hasOutputs() {
+		return false
+	}
+	return m.receiver.settings.emptyOutput != "" || m.emptyOutput() != EmptyOutputBlock
+}
+
+// emptyOutputCase returns a select case which follows m's empty output
+// policy: the policy stored in its empty output field or, if that is
+// empty, the policy that it was generated with.
+func (m Method) emptyOutputCase(sig signature) *ast.CommClause {
+	recv := m.receiver.receiverName()
+	policy := &ast.CallExpr{
+		Fun: selectors(recv, m.receiver.fields().emptyOutput),
+		Args: []ast.Expr{
+			selectors(recv, m.fieldNames().emptyOutput),
+			&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(m.emptyOutput())},
+			&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(m.name)},
+		},
+	}
+	for _, param := range sig.params {
+		for _, n := range param.Names {
+			policy.Args = append(policy.Args, &ast.Ident{Name: n.Name})
+		}
+	}
+	return &ast.CommClause{
+		Comm: &ast.AssignStmt{
+			Lhs: []ast.Expr{&ast.Ident{Name: sig.msg}},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{&ast.UnaryExpr{Op: token.ARROW, X: policy}},
+		},
+		Body: []ast.Stmt{&ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: &ast.Ident{Name: sig.msg}, Op: token.NEQ, Y: &ast.BasicLit{Kind: token.STRING, Value: `""`}},
+			Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ExprStmt{X: &ast.CallExpr{
+				Fun:  &ast.Ident{Name: "panic"},
+				Args: []ast.Expr{&ast.Ident{Name: sig.msg}},
+			}}}},
+		}},
+	}
+}
+
+// emptyOutputSrc is the source of the helper method that mocks use to
+// follow their methods' empty output policies.  It is formatted with
+// the receiver name, the mock type name, the helper's name, and then
+// the names of its variables.
+const emptyOutputSrc = `func (%[1]s *%[2]s) %[3]s(%[4]s, %[11]s, %[5]s string, %[6]s ...interface{}) <-chan string {
+	if %[4]s == "" {
+		%[4]s = %[11]s
+	}
+	const %[7]s = "%[2]s.%%s was called with %%v, but no output was queued"
+	%[8]s := make(chan string, 1)
+	switch %[4]s {
+	case "block":
+		return nil
+	case "zero":
+		close(%[8]s)
+	case "panic":
+		panic(fmt.Sprintf(%[7]s, %[5]s, %[6]s))
+	default:
+		%[9]s, %[10]s := time.ParseDuration(%[4]s)
+		if %[10]s != nil {
+			panic(fmt.Sprintf("%[2]s.%%s: invalid empty output policy %%q", %[5]s, %[4]s))
+		}
+		time.AfterFunc(%[9]s, func() {
+			%[8]s <- fmt.Sprintf(%[7]s+" within %%s", %[5]s, %[6]s, %[9]s)
+		})
+	}
+	return %[8]s
+}
+`
+
+// emptyOutputHelper returns the helper method that m's methods use to
+// follow their empty output policies, or nil if none of them need it.
+func (m Mock) emptyOutputHelper() *ast.FuncDecl {
+	name := m.fields().emptyOutput
+	if name == "" {
+		return nil
+	}
+	recv := m.receiverName()
+	vars := newVarScope(recv)
+	src := fmt.Sprintf(emptyOutputSrc, recv, m.Name(), name,
+		vars.name("policy"), vars.name("method"), vars.name("args"), vars.name("msg"), vars.name("done"),
+		vars.name("timeout"), vars.name("err"), vars.name("fallback"))
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package mocks\n\n"+src, 0)
+	if err != nil {
+		// The source is generated from a constant, so this would be a
+		// bug in hel.
+		panic(fmt.Errorf("hel: could not parse empty output helper: %s", err))
+	}
+	decl := f.Decls[0].(*ast.FuncDecl)
+	clearPositions(decl)
+	decl.Doc = commentGroup(wrap(fmt.Sprintf("%s returns a channel that the methods of %s, when called with no "+
+		"output queued, select on along with their output.  It follows policy, or fallback (the policy that the "+
+		"method was generated with) if policy is empty.  The channel is closed if they should return zero values, or "+
+		"receives a message if they should panic.  It panics straight away for the %q policy.  The arguments are only "+
+		"formatted when the method panics.",
+		name, m.Name(), EmptyOutputPanic), docWidth))
+	return decl
+}
+
+// clearPositions removes the positions from n, which was parsed from
+// separate source, so that it is printed like the rest of the
+// generated code rather than according to its original layout.
+func clearPositions(n ast.Node) {
+	posType := reflect.TypeOf(token.NoPos)
+	var empty []*ast.FieldList
+	ast.Inspect(n, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		v := reflect.ValueOf(n).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.Type() == posType {
+				f.SetInt(int64(token.NoPos))
+			}
+		}
+		if inter, ok := n.(*ast.InterfaceType); ok && len(inter.Methods.List) == 0 {
+			empty = append(empty, inter.Methods)
+		}
+		return true
+	})
+	for _, list := range empty {
+		// The printer only prints interface{} on one line if its
+		// braces have positions on the same line.
+		list.Opening, list.Closing = 1, 1
+	}
+}
@@ -0,0 +1,90 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks_test
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/a8m/expect"
+	"github.com/nelsam/hel/mocks"
+)
+
+// emptyOutputFoo is the interface that emptyOutputOverrideTest's mock
+// is generated from.
+const emptyOutputFoo = `type Foo interface {
+	Foo() (int, error)
+}`
+
+// emptyOutputReader is an io.Reader style interface, whose callers
+// write into the slice that they are passed.
+const emptyOutputReader = `type Reader interface {
+	Read(p []byte) (n int, err error)
+}`
+
+// emptyOutputOverrideTest is run against mocks of Foo and Reader which
+// were generated with the block policy.
+const emptyOutputOverrideTest = `package foo
+
+import "testing"
+
+func TestOverride(t *testing.T) {
+	m := newMockFoo()
+
+	m.FooEmptyOutput = "zero"
+	if v, err := m.Foo(); v != 0 || err != nil {
+		t.Fatalf("expected zero values; got %v, %v", v, err)
+	}
+
+	m.FooOutput.Ret0 <- 1
+	m.FooOutput.Ret1 <- nil
+	if v, _ := m.Foo(); v != 1 {
+		t.Fatalf("expected queued output to be returned; got %v", v)
+	}
+
+	m.FooEmptyOutput = "panic"
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected Foo to panic")
+		}
+	}()
+	m.Foo()
+}
+
+func TestBlockDoesNotReadArgs(t *testing.T) {
+	m := newMockReader()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Read(make([]byte, 4))
+	}()
+	p := <-m.ReadInput.P
+	copy(p, "data")
+	m.ReadOutput.N <- len(p)
+	m.ReadOutput.Err <- nil
+	<-done
+}
+`
+
+func TestEmptyOutput_RuntimeOverride(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, emptyOutputFoo),
+		typeSpec(expect, emptyOutputReader),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	err = m.SetEmptyOutput(mocks.EmptyOutputBlock)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	// Blocking methods must not read their arguments, which tests may
+	// be writing to, so the test is run with the race detector.
+	runGenerated(t, expect, m, emptyOutputFoo+"\n\n"+emptyOutputReader, emptyOutputOverrideTest)
+}
@@ -0,0 +1,383 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+const (
+	// generatedMarker follows the convention for marking generated
+	// code (see https://golang.org/s/generatedcode), so that tools
+	// will recognize the files that hel writes.
+	generatedMarker = "// Code generated by github.com/nelsam/hel. DO NOT EDIT."
+
+	// legacyMarker is the first line of the header that older versions
+	// of hel wrote.
+	legacyMarker = "// This file was generated by github.com/nelsam/hel.  Do not"
+
+	directivePrefix  = "//hel:"
+	versionDirective = "version"
+	argsDirective    = "args"
+	layoutDirective  = "layout"
+	typesDirective   = "types"
+	hashDirective    = "hash"
+	sumDirective     = "sum"
+)
+
+// Header is the metadata that hel records at the top of the files
+// it generates.
+type Header struct {
+	// License is text (such as a license) which is written above the
+	// generated code marker.
+	License string
+
+	// Version is the version of hel that generated the file.
+	Version string
+
+	// Args are the command line options that hel was run with.
+	Args []string
+
+	// Layout identifies the set of files that the file was generated
+	// along with (e.g. the template that split output was named by),
+	// so that files which are no longer generated can be found.
+	Layout []string
+
+	// Types are the interface types that the file's mocks were
+	// generated from.
+	Types []string
+
+	// Hash is a hash of the interface definitions, generator options,
+	// and version of hel that the file was generated from.
+	Hash string
+
+	// Sum is a checksum of the generated code following the
+	// header, used to detect changes made by hand.
+	Sum string
+}
+
+// An OutputOption is an option that affects the header of generated
+// files.
+type OutputOption func(*Header)
+
+// WithArgs returns an OutputOption which records args as the command
+// line options that hel was run with.
+func WithArgs(args ...string) OutputOption {
+	return func(h *Header) {
+		h.Args = args
+	}
+}
+
+// WithLayout returns an OutputOption which records layout as the
+// layout of the files that hel is generating.
+func WithLayout(layout ...string) OutputOption {
+	return func(h *Header) {
+		h.Layout = layout
+	}
+}
+
+// WithLicense returns an OutputOption which writes text above the
+// generated code marker.  Any lines of text which are not already
+// comments will be commented out.
+func WithLicense(text string) OutputOption {
+	return func(h *Header) {
+		h.License = commentLines(text)
+	}
+}
+
+func commentLines(text string) string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "//"):
+		case strings.TrimSpace(line) == "":
+			lines[i] = "//"
+		default:
+			lines[i] = "// " + line
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// writeProvenance writes the lines of h which describe where the
+// generated code came from.
+func (h Header) writeProvenance(w io.Writer) error {
+	if h.License != "" {
+		if _, err := fmt.Fprintf(w, "%s\n", h.License); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "%s\n//\n", generatedMarker); err != nil {
+		return err
+	}
+	return h.writeSource(w)
+}
+
+// writeSource writes the directives of h which record the version of
+// hel, the options, and the interfaces that the code was generated
+// with.
+func (h Header) writeSource(w io.Writer) error {
+	if err := writeDirective(w, versionDirective, h.Version); err != nil {
+		return err
+	}
+	if len(h.Args) > 0 {
+		if err := writeDirective(w, argsDirective, joinArgs(h.Args)); err != nil {
+			return err
+		}
+	}
+	if len(h.Layout) > 0 {
+		if err := writeDirective(w, layoutDirective, joinArgs(h.Layout)); err != nil {
+			return err
+		}
+	}
+	return writeDirective(w, typesDirective, strings.Join(h.Types, ", "))
+}
+
+func (h Header) write(w io.Writer) error {
+	if err := h.writeProvenance(w); err != nil {
+		return err
+	}
+	if err := writeDirective(w, hashDirective, h.Hash); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Directives returns the directives in h (e.g. //hel:version), one per
+// line, as they are written in the header of a generated file.
+func (h Header) Directives() string {
+	var b strings.Builder
+	// Writes to a strings.Builder don't fail.
+	h.writeSource(&b)
+	if h.Hash != "" {
+		writeDirective(&b, hashDirective, h.Hash)
+	}
+	if h.Sum != "" {
+		writeDirective(&b, sumDirective, h.Sum)
+	}
+	return b.String()
+}
+
+func writeDirective(w io.Writer, directive, value string) error {
+	_, err := fmt.Fprintf(w, "%s%s %s\n", directivePrefix, directive, value)
+	return err
+}
+
+// ReadHeader reads the header from the top of a file that hel
+// generated.  If r does not start with hel's header, ok will be
+// false.
+func ReadHeader(r io.Reader) (h Header, ok bool, err error) {
+	var license []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" && !ok {
+			// Blank lines may separate a license from the marker.
+			license = append(license, line)
+			continue
+		}
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+		if isMarker(line) {
+			ok = true
+			continue
+		}
+		if !ok {
+			license = append(license, line)
+			continue
+		}
+		if !strings.HasPrefix(line, directivePrefix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(line, directivePrefix), " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[0] {
+		case versionDirective:
+			h.Version = parts[1]
+		case argsDirective:
+			h.Args = splitArgs(parts[1])
+		case layoutDirective:
+			h.Layout = splitArgs(parts[1])
+		case typesDirective:
+			h.Types = strings.Split(parts[1], ", ")
+		case hashDirective:
+			h.Hash = parts[1]
+		case sumDirective:
+			h.Sum = parts[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Header{}, false, err
+	}
+	if !ok {
+		return Header{}, false, nil
+	}
+	if text := strings.Trim(strings.Join(license, "\n"), "\n"); text != "" {
+		h.License = text + "\n"
+	}
+	return h, true, nil
+}
+
+func isMarker(line string) bool {
+	return line == generatedMarker || line == legacyMarker
+}
+
+// Version returns the version of hel, as recorded in the build info
+// of the running binary.
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	const helPath = "github.com/nelsam/hel"
+	if info.Main.Path == helPath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != helPath {
+			continue
+		}
+		version := dep.Version
+		if dep.Replace != nil {
+			version = dep.Replace.Version
+		}
+		if version == "" {
+			// Replaced by a local directory.
+			return "(devel)"
+		}
+		return version
+	}
+	return "unknown"
+}
+
+// joinArgs joins args with spaces, quoting any args that would
+// otherwise be ambiguous.
+func joinArgs(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"\\") {
+			arg = strconv.Quote(arg)
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// splitArgs is the inverse of joinArgs.
+func splitArgs(s string) (args []string) {
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		end := strings.IndexByte(s, ' ')
+		if s[0] == '"' {
+			end = quotedEnd(s)
+		}
+		if end < 0 {
+			end = len(s)
+		}
+		arg := s[:end]
+		if unquoted, err := strconv.Unquote(arg); err == nil {
+			arg = unquoted
+		}
+		args = append(args, arg)
+		s = s[end:]
+	}
+	return args
+}
+
+// quotedEnd returns the index just past the closing quote of the
+// quoted string at the start of s.
+func quotedEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// Sign records a checksum of the generated code in src, which must
+// start with a header written by hel, in src's header.  It should be
+// called after any formatting (e.g. goimports) has been done, right
+// before src is written.
+func Sign(src []byte) ([]byte, error) {
+	end, ok := headerEnd(src)
+	if !ok {
+		return nil, errors.New("hel: cannot sign source without a hel header")
+	}
+	header := removeDirective(src[:end], sumDirective)
+	// The header ends with an empty line, which should still separate
+	// it from the generated code.
+	signed := append([]byte(nil), header[:len(header)-1]...)
+	signed = append(signed, fmt.Sprintf("%s%s %s\n\n", directivePrefix, sumDirective, hash(src[end:]))...)
+	return append(signed, src[end:]...), nil
+}
+
+// Intact reports whether the generated code in src still matches the
+// checksum recorded in its header by Sign.  Files without a checksum
+// (including files not generated by hel) are considered intact.
+func Intact(src []byte) (bool, error) {
+	h, ok, err := ReadHeader(bytes.NewReader(src))
+	if err != nil {
+		return false, err
+	}
+	if !ok || h.Sum == "" {
+		return true, nil
+	}
+	end, _ := headerEnd(src)
+	return hash(src[end:]) == h.Sum, nil
+}
+
+// headerEnd returns the index in src just past the empty line that
+// ends hel's header.
+func headerEnd(src []byte) (int, bool) {
+	start := bytes.Index(src, []byte(generatedMarker+"\n"))
+	if start < 0 {
+		start = bytes.Index(src, []byte(legacyMarker+"\n"))
+	}
+	if start < 0 {
+		return 0, false
+	}
+	end := bytes.Index(src[start:], []byte("\n\n"))
+	if end < 0 {
+		return 0, false
+	}
+	return start + end + 2, true
+}
+
+func removeDirective(header []byte, directive string) []byte {
+	prefix := []byte(directivePrefix + directive + " ")
+	var out []byte
+	for _, line := range bytes.SplitAfter(header, []byte("\n")) {
+		if bytes.HasPrefix(line, prefix) {
+			continue
+		}
+		out = append(out, line...)
+	}
+	return out
+}
+
+func hash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,290 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks_test
+
+import (
+	"bytes"
+	"go/ast"
+	"strings"
+	"testing"
+
+	"github.com/a8m/expect"
+	"github.com/nelsam/hel/mocks"
+)
+
+func TestOutputHeader(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Bar() int
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	hash, err := m.Hash("foo", "test/withoutimports", 100, mocks.WithArgs("-t", "Foo", "-o", "some file_test.go"))
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	buf := bytes.Buffer{}
+	err = m.Output("foo", "test/withoutimports", 100, &buf, mocks.WithArgs("-t", "Foo", "-o", "some file_test.go"))
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	expected := `// Code generated by github.com/nelsam/hel. DO NOT EDIT.
+//
+//hel:version ` + mocks.Version() + `
+//hel:args -t Foo -o "some file_test.go"
+//hel:types Foo
+//hel:hash ` + hash + `
+
+`
+	expect(strings.HasPrefix(buf.String(), expected)).To.Be.Ok()
+
+	h, ok, err := mocks.ReadHeader(&buf)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(ok).To.Be.Ok().Else.FailNow()
+	expect(h).To.Equal(mocks.Header{
+		Version: mocks.Version(),
+		Args:    []string{"-t", "Foo", "-o", "some file_test.go"},
+		Types:   []string{"Foo"},
+		Hash:    hash,
+	})
+	expect(h.Directives()).To.Equal(strings.TrimPrefix(strings.TrimSuffix(expected, "\n"), "// Code generated by github.com/nelsam/hel. DO NOT EDIT.\n//\n"))
+}
+
+func TestReadHeader_Legacy(t *testing.T) {
+	expect := expect.New(t)
+
+	src := `// This file was generated by github.com/nelsam/hel.  Do not
+// edit this code by hand unless you *really* know what you're
+// doing.  Expect any changes made manually to be overwritten
+// the next time hel regenerates this file.
+
+package foo
+`
+	h, ok, err := mocks.ReadHeader(strings.NewReader(src))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(ok).To.Be.Ok()
+	expect(h).To.Equal(mocks.Header{})
+}
+
+func TestHash(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Bar() int
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	hash, err := m.Hash("foo", "test/withoutimports", 100)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	again, err := m.Hash("foo", "test/withoutimports", 100)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(again).To.Equal(hash)
+
+	differentSize, err := m.Hash("foo", "test/withoutimports", 10)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(differentSize == hash).To.Equal(false)
+
+	differentPkg, err := m.Hash("foo_test", "test/withoutimports", 100)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(differentPkg == hash).To.Equal(false)
+
+	differentArgs, err := m.Hash("foo", "test/withoutimports", 100, mocks.WithArgs("-t", "Foo"))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(differentArgs).To.Equal(hash)
+
+	differentLayout, err := m.Hash("foo", "test/withoutimports", 100, mocks.WithLayout("{{.Interface}}_test.go"))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(differentLayout == hash).To.Equal(false)
+
+	m.SetChanSizes([]mocks.ChanSize{{Size: 100}})
+	sameSizes, err := m.Hash("foo", "test/withoutimports", 10)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(sameSizes).To.Equal(hash)
+	m.SetChanSizes(nil)
+
+	m.SetBlockingReturn(true)
+	blocking, err := m.Hash("foo", "test/withoutimports", 100)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(blocking).To.Equal(hash)
+	m.SetBlockingReturn(false)
+
+	// An explicit block policy generates runtime overrides, so it
+	// changes the output.
+	m.SetEmptyOutput(mocks.EmptyOutputBlock)
+	block, err := m.Hash("foo", "test/withoutimports", 100)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(block == hash).To.Equal(false)
+
+	m.SetEmptyOutput(mocks.EmptyOutputZero)
+	zero, err := m.Hash("foo", "test/withoutimports", 100)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(zero == block).To.Equal(false)
+
+	m.SetEmptyOutput(mocks.EmptyOutputBlock)
+	again, err = m.Hash("foo", "test/withoutimports", 100)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(again).To.Equal(block)
+}
+
+func TestReadHeader_NotGenerated(t *testing.T) {
+	expect := expect.New(t)
+
+	_, ok, err := mocks.ReadHeader(strings.NewReader("// Some hand-written file.\n\npackage foo\n"))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(ok).To.Equal(false)
+}
+
+func TestSign(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Bar() int
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	buf := bytes.Buffer{}
+	err = m.Output("foo", "test/withoutimports", 100, &buf)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	intact, err := mocks.Intact(buf.Bytes())
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(intact).To.Be.Ok()
+
+	signed, err := mocks.Sign(buf.Bytes())
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, string(signed))).To.Equal(body(expect, buf.String()))
+
+	h, ok, err := mocks.ReadHeader(bytes.NewReader(signed))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(ok).To.Be.Ok().Else.FailNow()
+	expect(h.Sum).Not.To.Equal("")
+
+	intact, err = mocks.Intact(signed)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(intact).To.Be.Ok()
+
+	resigned, err := mocks.Sign(signed)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(string(resigned)).To.Equal(string(signed))
+
+	edited := bytes.Replace(signed, []byte("<-m.BarOutput.Ret0"), []byte("42"), 1)
+	intact, err = mocks.Intact(edited)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(intact).To.Equal(false)
+
+	_, err = mocks.Sign([]byte("package foo\n"))
+	expect(err).Not.To.Be.Nil()
+}
+
+func TestOutputHeader_License(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Bar() int
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	license := mocks.WithLicense("Copyright 2026 Foo\n\nAll rights reserved.\n")
+	hash, err := m.Hash("foo", "test/withoutimports", 100, license)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	unlicensed, err := m.Hash("foo", "test/withoutimports", 100)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(unlicensed == hash).To.Equal(false)
+
+	buf := bytes.Buffer{}
+	err = m.Output("foo", "test/withoutimports", 100, &buf, license)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	expected := `// Copyright 2026 Foo
+//
+// All rights reserved.
+
+// Code generated by github.com/nelsam/hel. DO NOT EDIT.
+//
+`
+	expect(strings.HasPrefix(buf.String(), expected)).To.Be.Ok()
+
+	signed, err := mocks.Sign(buf.Bytes())
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(strings.HasPrefix(string(signed), expected)).To.Be.Ok()
+
+	intact, err := mocks.Intact(signed)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(intact).To.Be.Ok()
+
+	h, ok, err := mocks.ReadHeader(bytes.NewReader(signed))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(ok).To.Be.Ok().Else.FailNow()
+	expect(h.License).To.Equal("// Copyright 2026 Foo\n//\n// All rights reserved.\n")
+	expect(h.Hash).To.Equal(hash)
+}
+
+func TestOutputHeader_Layout(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Bar() int
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	layout := mocks.WithLayout("mock {{.Interface}}_test.go", "Foo")
+	buf := bytes.Buffer{}
+	err = m.Output("foo", "test/withoutimports", 100, &buf, layout)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	expected := `// Code generated by github.com/nelsam/hel. DO NOT EDIT.
+//
+//hel:version ` + mocks.Version() + `
+//hel:layout "mock {{.Interface}}_test.go" Foo
+//hel:types Foo
+`
+	expect(strings.HasPrefix(buf.String(), expected)).To.Be.Ok()
+
+	h, ok, err := mocks.ReadHeader(&buf)
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(ok).To.Be.Ok().Else.FailNow()
+	expect(h.Layout).To.Equal([]string{"mock {{.Interface}}_test.go", "Foo"})
+}
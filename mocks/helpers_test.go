@@ -10,8 +10,16 @@ import (
 	"go/format"
 	"go/parser"
 	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
 
 	"github.com/a8m/expect"
+	"github.com/nelsam/hel/mocks"
+	"golang.org/x/tools/imports"
 )
 
 const packagePrefix = "package foo\n\n"
@@ -25,7 +33,23 @@ func source(expect func(interface{}) *expect.Expect, pkg string, decls []ast.Dec
 	}
 	err := format.Node(&buf, token.NewFileSet(), f)
 	expect(err).To.Be.Nil()
-	return buf.String()
+	src, err := format.Source(buf.Bytes())
+	expect(err).To.Be.Nil()
+	return string(src)
+}
+
+// documentedTypeSpec parses spec along with its comments, attaching
+// the doc comment of its declaration the way types.Dir does.
+func documentedTypeSpec(expect func(interface{}) *expect.Expect, spec string) *ast.TypeSpec {
+	f, err := parser.ParseFile(token.NewFileSet(), "", packagePrefix+spec, parser.ParseComments)
+	expect(err).To.Be.Nil()
+	expect(f.Decls).To.Have.Len(1)
+	decl := f.Decls[0].(*ast.GenDecl)
+	typ := decl.Specs[0].(*ast.TypeSpec)
+	if typ.Doc == nil {
+		typ.Doc = decl.Doc
+	}
+	return typ
 }
 
 func parse(expect func(interface{}) *expect.Expect, code string) *ast.File {
@@ -54,3 +78,53 @@ func method(expect func(interface{}) *expect.Expect, spec *ast.TypeSpec) *ast.Fu
 	expect(ok).To.Be.Ok()
 	return f
 }
+
+// body strips the header that hel writes to generated files from
+// src, returning only the generated code.
+func body(expect func(interface{}) *expect.Expect, src string) string {
+	parts := strings.SplitN(src, "\n\n", 2)
+	expect(parts).To.Have.Len(2)
+	return parts[len(parts)-1]
+}
+
+// runGenerated writes the mocks in m to a temporary package foo, along
+// with the interfaces declared in decls and the tests in test, and runs
+// go test on it (with the race detector, if cgo is enabled).
+func runGenerated(t *testing.T, expect func(interface{}) *expect.Expect, m mocks.Mocks, decls, test string) {
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is required to run generated mocks")
+	}
+
+	buf := bytes.Buffer{}
+	err = m.Output("foo", "test/withoutimports", 100, &buf)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	dir, err := ioutil.TempDir("", "hel-generated")
+	expect(err).To.Be.Nil().Else.FailNow()
+	defer os.RemoveAll(dir)
+
+	mockPath := filepath.Join(dir, "helheim_test.go")
+	src, err := imports.Process(mockPath, buf.Bytes(), nil)
+	expect(err).To.Be.Nil().Else.FailNow()
+	files := map[string][]byte{
+		"go.mod":          []byte("module foo\n"),
+		"foo.go":          []byte(packagePrefix + decls + "\n"),
+		"helheim_test.go": src,
+		"foo_test.go":     []byte(test),
+	}
+	for name, contents := range files {
+		err = ioutil.WriteFile(filepath.Join(dir, name), contents, 0644)
+		expect(err).To.Be.Nil().Else.FailNow()
+	}
+
+	args := []string{"test", "-timeout", "30s"}
+	if cgo, err := exec.Command(goPath, "env", "CGO_ENABLED").Output(); err == nil && strings.TrimSpace(string(cgo)) == "1" {
+		args = append(args, "-race")
+	}
+	cmd := exec.Command(goPath, append(args, ".")...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("running the generated mocks failed: %s\n%s", err, out)
+	}
+}
@@ -0,0 +1,138 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// drainSrc is the source of the helper method that mocks with
+// lifecycle methods use to drain their channels.  It is formatted with
+// the receiver name, the mock type name, the helper's name, and then
+// the names of its variables.
+const drainSrc = `func (%[1]s *%[2]s) %[3]s(%[4]s ...interface{}) {
+	for _, %[5]s := range %[4]s {
+		%[6]s := reflect.ValueOf(%[5]s)
+		for {
+			if _, %[7]s := %[6]s.TryRecv(); !%[7]s {
+				break
+			}
+		}
+	}
+}
+`
+
+// emptyStruct returns the type struct{}.
+func emptyStruct() *ast.StructType {
+	// The printer only prints struct{} on one line if its braces have
+	// positions on the same line.
+	return &ast.StructType{Fields: &ast.FieldList{Opening: 1, Closing: 1}}
+}
+
+// closedCase returns a select case which receives from the channel
+// that is closed when m's mock is closed.
+func (m Method) closedCase() *ast.CommClause {
+	return &ast.CommClause{Comm: &ast.ExprStmt{X: &ast.UnaryExpr{
+		Op: token.ARROW,
+		X:  selectors(m.receiver.receiverName(), m.receiver.fields().closed),
+	}}}
+}
+
+// lifecycleFields returns the fields that m's lifecycle methods use to
+// close m.
+func (m Mock) lifecycleFields() []*ast.Field {
+	fields := m.fields()
+	return []*ast.Field{
+		{
+			Names: []*ast.Ident{{Name: fields.closed}},
+			Type:  &ast.ChanType{Dir: ast.SEND | ast.RECV, Value: emptyStruct()},
+		},
+		{
+			Names: []*ast.Ident{{Name: fields.closeOnce}},
+			Type:  selectors("sync", "Once"),
+		},
+	}
+}
+
+// lifecycleSrc returns the source of m's lifecycle methods.
+func (m Mock) lifecycleSrc() string {
+	fields := m.fields()
+	recv := m.receiverName()
+	vars := newVarScope(recv)
+	var b strings.Builder
+	fmt.Fprintf(&b, "func (%s *%s) %s() {\n", recv, m.Name(), fields.closeMethod)
+	fmt.Fprintf(&b, "%s.%s.Do(func() { close(%s.%s) })\n}\n\n", recv, fields.closeOnce, recv, fields.closed)
+
+	var chans []string
+	for _, method := range m.Methods() {
+		sig := method.signature()
+		names := method.fieldNames()
+		chans = append(chans, recv+"."+names.called)
+		for _, list := range []struct {
+			name   string
+			fields []*ast.Field
+		}{{names.input, sig.inputs}, {names.output, sig.outputs}} {
+			switch {
+			case len(list.fields) == 0:
+			case m.settings.records:
+				chans = append(chans, recv+"."+list.name)
+			default:
+				for _, f := range list.fields {
+					for _, n := range f.Names {
+						chans = append(chans, recv+"."+list.name+"."+n.Name)
+					}
+				}
+			}
+		}
+	}
+	fmt.Fprintf(&b, "func (%s *%s) %s() {\n", recv, m.Name(), fields.reset)
+	if m.settings.unbounded {
+		// The first value for each channel is being sent by the flush
+		// helper, which removes it once it has been sent.
+		ch, pending := vars.name("ch"), vars.name("pending")
+		fmt.Fprintf(&b, "%s.%s.Lock()\n", recv, fields.backlog)
+		fmt.Fprintf(&b, "for %s, %s := range %s.%s.values {\n", ch, pending, recv, fields.backlog)
+		fmt.Fprintf(&b, "%s.%s.values[%s] = %s[:1]\n}\n", recv, fields.backlog, ch, pending)
+		fmt.Fprintf(&b, "%s.%s.Unlock()\n", recv, fields.backlog)
+	}
+	fmt.Fprintf(&b, "%s.%s(%s)\n}\n", recv, fields.drain, strings.Join(chans, ", "))
+	return b.String()
+}
+
+// lifecycleMethods returns m's lifecycle methods (Close and Reset)
+// and the helper method that they use, or nil if m does not have
+// lifecycle methods.
+func (m Mock) lifecycleMethods() []ast.Decl {
+	if !m.settings.lifecycle {
+		return nil
+	}
+	fields := m.fields()
+	recv := m.receiverName()
+	vars := newVarScope(recv)
+	src := m.lifecycleSrc() + "\n" + fmt.Sprintf(drainSrc, recv, m.Name(), fields.drain,
+		vars.name("chans"), vars.name("ch"), vars.name("c"), vars.name("ok"))
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package mocks\n\n"+src, 0)
+	if err != nil {
+		// The source is generated from constants and identifiers, so
+		// this would be a bug in hel.
+		panic(fmt.Errorf("hel: could not parse lifecycle methods: %s", err))
+	}
+	closeMethod, reset, drain := f.Decls[0].(*ast.FuncDecl), f.Decls[1].(*ast.FuncDecl), f.Decls[2].(*ast.FuncDecl)
+	for _, decl := range []*ast.FuncDecl{closeMethod, reset, drain} {
+		clearPositions(decl)
+	}
+	closeMethod.Doc = commentGroup(wrap(fmt.Sprintf("%s releases calls to %s that are waiting for output, which "+
+		"return zero values.  Calls made after %s return output that has already been queued, or zero values, and "+
+		"may not be sent on their called and input channels.", fields.closeMethod, m.Name(), fields.closeMethod),
+		docWidth))
+	reset.Doc = commentGroup(wrap(fmt.Sprintf("%s drains all of %s's channels, so that it may be reused (e.g. by "+
+		"subtests).", fields.reset, m.Name()), docWidth))
+	drain.Doc = commentGroup(wrap(fmt.Sprintf("%s receives the values left in chans.", fields.drain), docWidth))
+	return []ast.Decl{closeMethod, reset, drain}
+}
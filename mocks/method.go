@@ -9,14 +9,12 @@ import (
 	"go/ast"
 	"go/token"
 	"strconv"
-	"strings"
 	"unicode"
 )
 
 const (
-	inputFmt     = "arg%d"
-	outputFmt    = "ret%d"
-	receiverName = "m"
+	inputFmt  = "arg%d"
+	outputFmt = "ret%d"
 )
 
 // Method represents a method that is being mocked.
@@ -24,6 +22,7 @@ type Method struct {
 	receiver   Mock
 	name       string
 	implements *ast.FuncType
+	doc        *ast.CommentGroup
 }
 
 // MethodFor returns a Method representing typ, using receiver as
@@ -39,6 +38,7 @@ func MethodFor(receiver Mock, name string, typ *ast.FuncType) Method {
 // Ast returns the ast representation of m.
 func (m Method) Ast() *ast.FuncDecl {
 	f := &ast.FuncDecl{}
+	f.Doc = commentGroup(m.receiver.copiedDoc(m.doc))
 	f.Name = &ast.Ident{Name: m.name}
 	f.Type = m.mockType()
 	f.Recv = m.recv()
@@ -49,30 +49,122 @@ func (m Method) Ast() *ast.FuncDecl {
 // Fields returns the fields that need to be a part of the receiver
 // struct for this method.
 func (m Method) Fields() []*ast.Field {
+	sig := m.signature()
+	names := m.fieldNames()
 	fields := []*ast.Field{
 		{
-			Names: []*ast.Ident{{Name: m.name + "Called"}},
+			Names: []*ast.Ident{{Name: names.called}},
 			Type: &ast.ChanType{
 				Dir:   ast.SEND | ast.RECV,
 				Value: &ast.Ident{Name: "bool"},
 			},
+			Tag: m.tag("called", names.called),
 		},
 	}
-	if len(m.params()) > 0 {
+	if len(sig.inputs) > 0 {
 		fields = append(fields, &ast.Field{
-			Names: []*ast.Ident{{Name: m.name + "Input"}},
-			Type:  m.chanStruct(m.implements.Params.List),
+			Names: []*ast.Ident{{Name: names.input}},
+			Type:  m.valuesType(sig.inputs),
+			Tag:   m.tag("input", names.input),
 		})
 	}
-	if len(m.results()) > 0 {
+	if len(sig.outputs) > 0 {
 		fields = append(fields, &ast.Field{
-			Names: []*ast.Ident{{Name: m.name + "Output"}},
-			Type:  m.chanStruct(m.results()),
+			Names: []*ast.Ident{{Name: names.output}},
+			Type:  m.valuesType(sig.outputs),
+			Tag:   m.tag(m.outputRole(), names.output),
+		})
+	}
+	if names.sideEffect != "" {
+		fields = append(fields, &ast.Field{
+			Names: []*ast.Ident{{Name: names.sideEffect}},
+			Type:  &ast.FuncType{Params: &ast.FieldList{List: sig.params}},
+			Tag:   m.tag("sideEffect", names.sideEffect),
+		})
+	}
+	if names.emptyOutput != "" {
+		fields = append(fields, &ast.Field{
+			Names: []*ast.Ident{{Name: names.emptyOutput}},
+			Type:  &ast.Ident{Name: "string"},
+		})
+	}
+	if names.always != "" {
+		fields = append(fields, &ast.Field{
+			Names: []*ast.Ident{{Name: names.always}},
+			Type:  &ast.ChanType{Dir: ast.SEND | ast.RECV, Value: m.recordStruct(sig.outputs)},
 		})
 	}
 	return fields
 }
 
+// valuesType returns the type of the field that the values in list
+// are sent on: either a struct of channels or, when records are
+// enabled, a channel of record structs.
+func (m Method) valuesType(list []*ast.Field) ast.Expr {
+	if m.receiver.settings.records {
+		return &ast.ChanType{Dir: ast.SEND | ast.RECV, Value: m.recordStruct(list)}
+	}
+	return m.chanStruct(list)
+}
+
+// recordStruct returns the type of the record that holds all of the
+// values in list.
+func (m Method) recordStruct(list []*ast.Field) *ast.StructType {
+	typ := &ast.StructType{Fields: &ast.FieldList{}}
+	for _, f := range list {
+		valType := f.Type
+		if src, ok := valType.(*ast.Ellipsis); ok {
+			// The actual value of variadic types is a slice
+			valType = &ast.ArrayType{Elt: src.Elt}
+		}
+		typ.Fields.List = append(typ.Fields.List, &ast.Field{
+			Names: f.Names,
+			Type:  valType,
+		})
+	}
+	return typ
+}
+
+func (m Method) fieldNames() methodFields {
+	return m.receiver.fields().methods[m.name]
+}
+
+func (m Method) hasInputs() bool {
+	return m.implements.Params != nil && len(m.implements.Params.List) > 0
+}
+
+// hasResults returns whether m's interface method has results, unlike
+// hasOutputs, which is also true for the BlockReturn output of
+// --blocking-return.
+func (m Method) hasResults() bool {
+	return m.implements.Results != nil && len(m.implements.Results.List) > 0
+}
+
+func (m Method) hasOutputs() bool {
+	if m.implements.Results == nil {
+		return m.receiver.settings.blockingReturn
+	}
+	return len(m.implements.Results.List) > 0
+}
+
+// tag returns the struct tag that pers uses to find the field for
+// role when the field's name is not the default.
+func (m Method) tag(role, name string) *ast.BasicLit {
+	if !m.receiver.settings.naming.tagged && name == m.name+title(role) {
+		return nil
+	}
+	return &ast.BasicLit{Kind: token.STRING, Value: fmt.Sprintf("`hel:\"%s,%s\"`", m.name, role)}
+}
+
+// outputRole returns the role of m's output field: the results of a
+// spy are not outputs that pers may send return values on.
+func (m Method) outputRole() string {
+	if m.receiver.settings.spy {
+		return "results"
+	}
+	return "output"
+}
+
 func (m Method) chanStruct(list []*ast.Field) *ast.StructType {
 	typ := &ast.StructType{Fields: &ast.FieldList{}}
 	for _, f := range list {
@@ -89,15 +181,8 @@ func (m Method) chanStruct(list []*ast.Field) *ast.StructType {
 			// The actual value of variadic types is a slice
 			chanValType = &ast.ArrayType{Elt: src.Elt}
 		}
-		names := make([]*ast.Ident, 0, len(f.Names))
-		for _, name := range f.Names {
-			newName := &ast.Ident{}
-			*newName = *name
-			names = append(names, newName)
-			newName.Name = strings.Title(newName.Name)
-		}
 		typ.Fields.List = append(typ.Fields.List, &ast.Field{
-			Names: names,
+			Names: f.Names,
 			Type: &ast.ChanType{
 				Dir:   ast.SEND | ast.RECV,
 				Value: chanValType,
@@ -108,21 +193,29 @@ func (m Method) chanStruct(list []*ast.Field) *ast.StructType {
 }
 
 func (m Method) paramChanInit(chanSize int) []ast.Stmt {
-	if len(m.params()) == 0 {
+	sig := m.signature()
+	if len(sig.inputs) == 0 {
 		return nil
 	}
-	return m.typeChanInit(m.name+"Input", m.implements.Params.List, chanSize)
+	return m.typeChanInit(m.fieldNames().input, sig.inputs, chanSize)
 }
 
 func (m Method) returnChanInit(chanSize int) []ast.Stmt {
-	return m.typeChanInit(m.name+"Output", m.results(), chanSize)
+	return m.typeChanInit(m.fieldNames().output, m.signature().outputs, chanSize)
 }
 
 func (m Method) typeChanInit(fieldName string, fields []*ast.Field, chanSize int) (inputInits []ast.Stmt) {
+	if len(fields) > 0 && m.receiver.settings.records {
+		return []ast.Stmt{&ast.AssignStmt{
+			Lhs: []ast.Expr{selectors("m", fieldName)},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{m.makeChan(m.recordStruct(fields), chanSize)},
+		}}
+	}
 	for _, field := range fields {
 		for _, name := range field.Names {
 			inputInits = append(inputInits, &ast.AssignStmt{
-				Lhs: []ast.Expr{selectors("m", fieldName, strings.Title(name.String()))},
+				Lhs: []ast.Expr{selectors("m", fieldName, name.String())},
 				Tok: token.ASSIGN,
 				Rhs: []ast.Expr{m.makeChan(field.Type, chanSize)},
 			})
@@ -152,15 +245,28 @@ func (m Method) makeChan(typ ast.Expr, size int) *ast.CallExpr {
 }
 
 func (m Method) chanInit(chanSize int) []ast.Stmt {
+	inputSize := m.chanSize(ChanInput, chanSize)
 	stmts := []ast.Stmt{
 		&ast.AssignStmt{
-			Lhs: []ast.Expr{selectors("m", m.name+"Called")},
+			Lhs: []ast.Expr{selectors("m", m.fieldNames().called)},
 			Tok: token.ASSIGN,
-			Rhs: []ast.Expr{m.makeChan(&ast.Ident{Name: "bool"}, chanSize)},
+			Rhs: []ast.Expr{m.makeChan(&ast.Ident{Name: "bool"}, inputSize)},
 		},
 	}
-	stmts = append(stmts, m.paramChanInit(chanSize)...)
-	stmts = append(stmts, m.returnChanInit(chanSize)...)
+	stmts = append(stmts, m.paramChanInit(inputSize)...)
+	stmts = append(stmts, m.returnChanInit(m.chanSize(ChanOutput, chanSize))...)
+	if name := m.fieldNames().always; name != "" {
+		// The channel is unbuffered, so that values are only handed
+		// out as calls are made.
+		stmts = append(stmts, &ast.AssignStmt{
+			Lhs: []ast.Expr{selectors("m", name)},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{&ast.CallExpr{
+				Fun:  &ast.Ident{Name: "make"},
+				Args: []ast.Expr{&ast.ChanType{Dir: ast.SEND | ast.RECV, Value: m.recordStruct(m.signature().outputs)}},
+			}},
+		})
+	}
 	return stmts
 }
 
@@ -168,7 +274,7 @@ func (m Method) recv() *ast.FieldList {
 	return &ast.FieldList{
 		List: []*ast.Field{
 			{
-				Names: []*ast.Ident{{Name: receiverName}},
+				Names: []*ast.Ident{{Name: m.receiver.receiverName()}},
 				Type: &ast.StarExpr{
 					X: &ast.Ident{Name: m.receiver.Name()},
 				},
@@ -178,13 +284,13 @@ func (m Method) recv() *ast.FieldList {
 }
 
 func (m Method) mockType() *ast.FuncType {
-	newTyp := &ast.FuncType{
-		Results: m.implements.Results,
-	}
+	sig := m.signature()
+	newTyp := &ast.FuncType{}
 	if m.implements.Params != nil {
-		newTyp.Params = &ast.FieldList{
-			List: m.params(),
-		}
+		newTyp.Params = &ast.FieldList{List: sig.params}
+	}
+	if m.implements.Results != nil {
+		newTyp.Results = &ast.FieldList{List: sig.results}
 	}
 	return newTyp
 }
@@ -194,81 +300,172 @@ func (m Method) sendOn(receiver string, fields ...string) *ast.SendStmt {
 }
 
 func (m Method) called() ast.Stmt {
-	stmt := m.sendOn(receiverName, m.name+"Called")
-	stmt.Value = &ast.Ident{Name: "true"}
+	return m.send(m.sendOn(m.receiver.receiverName(), m.fieldNames().called), &ast.Ident{Name: "true"})
+}
+
+// send returns a statement which sends value on stmt's channel.  For
+// unbounded mocks, the value is enqueued instead, so that the
+// statement never blocks.  For mocks with lifecycle methods, the send
+// is abandoned if the mock is closed.
+func (m Method) send(stmt *ast.SendStmt, value ast.Expr) ast.Stmt {
+	if m.receiver.settings.unbounded {
+		return &ast.ExprStmt{X: &ast.CallExpr{
+			Fun:  selectors(m.receiver.receiverName(), m.receiver.fields().enqueue),
+			Args: []ast.Expr{stmt.Chan, value},
+		}}
+	}
+	stmt.Value = value
+	if m.receiver.settings.lifecycle {
+		return &ast.SelectStmt{Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.CommClause{Comm: stmt},
+			m.closedCase(),
+		}}}
+	}
 	return stmt
 }
 
-func mockField(idx int, f *ast.Field) *ast.Field {
-	if f.Names == nil {
-		if idx < 0 {
-			return f
+// signature holds the names used for a mocked method's parameters
+// and results, along with the fields of the structs that they are
+// sent on.
+type signature struct {
+	// params and results are the parameters and results of the mock
+	// method.  Unnamed parameters are named, and any names which would
+	// conflict with the receiver or with package names are changed.
+	params, results []*ast.Field
+
+	// inputs and outputs are the fields of the method's input and
+	// output structs.  They are grouped the same way as params and
+	// results.
+	inputs, outputs []*ast.Field
+
+	// record is the name of the variable that holds the output record
+	// when records are enabled.
+	record string
+
+	// msg and zeros are the names of the variables that hold the
+	// message from an empty output policy and the zero values of the
+	// results.
+	msg   string
+	zeros []string
+}
+
+// signature allocates the names used for m's parameters and results.
+func (m Method) signature() signature {
+	var sig signature
+	var types []ast.Expr
+	for _, list := range []*ast.FieldList{m.implements.Params, m.implements.Results} {
+		if list == nil {
+			continue
 		}
-		// Edit the field directly to ensure the same name is used in the mock
-		// struct.
-		f.Names = []*ast.Ident{{Name: fmt.Sprintf(inputFmt, idx)}}
-		return f
-	}
-
-	// Here, we want a copy, so that we can use altered names without affecting
-	// field names in the mock struct.
-	newField := &ast.Field{Type: f.Type}
-	for _, n := range f.Names {
-		name := n.Name
-		if name == receiverName {
-			name += "_"
+		for _, f := range list.List {
+			types = append(types, f.Type)
 		}
-		newField.Names = append(newField.Names, &ast.Ident{Name: name})
 	}
-	return newField
-}
+	vars := newVarScope(m.receiver.receiverName())
+	vars.reserve(packageNames(types...)...)
 
-func (m Method) params() []*ast.Field {
-	var params []*ast.Field
-	for idx, f := range m.implements.Params.List {
-		params = append(params, mockField(idx, f))
+	if m.implements.Params != nil {
+		inputs := newScope()
+		pos := 0
+		for _, f := range m.implements.Params.List {
+			param := &ast.Field{Type: f.Type}
+			input := &ast.Field{Type: f.Type}
+			names := f.Names
+			if names == nil {
+				names = []*ast.Ident{{Name: "_"}}
+			}
+			for _, n := range names {
+				name := n.Name
+				if name == "_" {
+					name = fmt.Sprintf(inputFmt, pos)
+				}
+				param.Names = append(param.Names, &ast.Ident{Name: vars.name(name)})
+				input.Names = append(input.Names, &ast.Ident{Name: inputs.name(fieldName(name))})
+				pos++
+			}
+			sig.params = append(sig.params, param)
+			sig.inputs = append(sig.inputs, input)
+		}
 	}
-	return params
-}
 
-func (m Method) results() []*ast.Field {
 	if m.implements.Results == nil {
-		if !*m.receiver.blockingReturn {
-			return nil
-		}
-		return []*ast.Field{
-			{
-				Names: []*ast.Ident{
-					{Name: "blockReturn"},
-				},
-				Type: &ast.Ident{Name: "bool"},
-			},
+		if m.receiver.settings.blockingReturn {
+			sig.outputs = []*ast.Field{{
+				Names: []*ast.Ident{{Name: "BlockReturn"}},
+				Type:  &ast.Ident{Name: "bool"},
+			}}
 		}
+		sig.msg = vars.name("msg")
+		return sig
 	}
-	fields := make([]*ast.Field, 0, len(m.implements.Results.List))
-	for idx, f := range m.implements.Results.List {
+	outputs := newScope()
+	pos := 0
+	for _, f := range m.implements.Results.List {
+		result := &ast.Field{Type: f.Type}
+		output := &ast.Field{Type: f.Type}
 		if f.Names == nil {
-			// to avoid changing the method definition, make a copy
-			copy := *f
-			f = &copy
-			f.Names = []*ast.Ident{{Name: fmt.Sprintf(outputFmt, idx)}}
+			output.Names = []*ast.Ident{{Name: outputs.name(fieldName(fmt.Sprintf(outputFmt, pos)))}}
+			pos++
 		}
-		fields = append(fields, f)
+		for _, n := range f.Names {
+			name := n.Name
+			if name == "_" {
+				result.Names = append(result.Names, &ast.Ident{Name: name})
+				name = fmt.Sprintf(outputFmt, pos)
+			} else {
+				result.Names = append(result.Names, &ast.Ident{Name: vars.name(name)})
+			}
+			output.Names = append(output.Names, &ast.Ident{Name: outputs.name(fieldName(name))})
+			pos++
+		}
+		sig.results = append(sig.results, result)
+		sig.outputs = append(sig.outputs, output)
 	}
-	return fields
+	sig.record = vars.name("out")
+	sig.msg = vars.name("msg")
+	for i := range m.implements.Results.List {
+		n := len(m.implements.Results.List[i].Names)
+		if n == 0 {
+			n = 1
+		}
+		for j := 0; j < n; j++ {
+			sig.zeros = append(sig.zeros, vars.name(fmt.Sprintf(outputFmt, len(sig.zeros))))
+		}
+	}
+	return sig
+}
+
+// fieldName returns an exported field name based on the variable
+// name v.
+func fieldName(v string) string {
+	name := title(v)
+	if !ast.IsExported(name) {
+		// e.g. _foo, which can't be capitalized.
+		name = "X" + name
+	}
+	return name
 }
 
 func (m Method) inputs() (stmts []ast.Stmt) {
-	for _, input := range m.params() {
-		for _, n := range input.Names {
-			// Undo our hack to avoid name collisions with the receiver.
-			name := n.Name
-			if name == receiverName+"_" {
-				name = receiverName
+	receiverName := m.receiver.receiverName()
+	inputName := m.fieldNames().input
+	sig := m.signature()
+	if m.receiver.settings.records {
+		if len(sig.inputs) == 0 {
+			return nil
+		}
+		record := &ast.CompositeLit{Type: m.recordStruct(sig.inputs)}
+		for _, param := range sig.params {
+			for _, n := range param.Names {
+				record.Elts = append(record.Elts, &ast.Ident{Name: n.Name})
 			}
-			stmt := m.sendOn(receiverName, m.name+"Input", strings.Title(name))
-			stmt.Value = &ast.Ident{Name: n.Name}
-			stmts = append(stmts, stmt)
+		}
+		return []ast.Stmt{m.send(m.sendOn(receiverName, inputName), record)}
+	}
+	for i, param := range sig.params {
+		for j, n := range param.Names {
+			stmt := m.sendOn(receiverName, inputName, sig.inputs[i].Names[j].Name)
+			stmts = append(stmts, m.send(stmt, &ast.Ident{Name: n.Name}))
 		}
 	}
 	return stmts
@@ -323,30 +520,249 @@ func (m Method) recvFrom(receiver string, fields ...string) *ast.UnaryExpr {
 }
 
 func (m Method) returnsExprs() (exprs []ast.Expr) {
-	for _, output := range m.results() {
+	outputName := m.fieldNames().output
+	for _, output := range m.signature().outputs {
 		for _, name := range output.Names {
-			exprs = append(exprs, m.recvFrom(receiverName, m.name+"Output", strings.Title(name.String())))
+			exprs = append(exprs, m.recvFrom(m.receiver.receiverName(), outputName, name.String()))
 		}
 	}
 	return exprs
 }
 
-func (m Method) returns() ast.Stmt {
+func (m Method) returns() []ast.Stmt {
+	if m.receiver.settings.spy {
+		return m.spyReturns()
+	}
+	if m.guarded() || m.hasOutputs() && m.receiver.settings.lifecycle || m.contextParam(m.signature()) != "" ||
+		m.fieldNames().always != "" {
+		return m.selectReturns()
+	}
+	if m.receiver.settings.records {
+		return m.recordReturns()
+	}
 	if m.implements.Results == nil {
-		if !*m.receiver.blockingReturn {
+		if !m.receiver.settings.blockingReturn {
 			return nil
 		}
-		return &ast.ExprStmt{X: m.returnsExprs()[0]}
+		return []ast.Stmt{&ast.ExprStmt{X: m.returnsExprs()[0]}}
+	}
+	return []ast.Stmt{&ast.ReturnStmt{Results: m.returnsExprs()}}
+}
+
+// selectReturns returns the statements which return m's output (or
+// the values from its AlwaysReturns helper), or zero values if m's
+// empty output policy (stored in its empty output field) says to, its
+// mock is closed, or its context is done before output is queued.
+func (m Method) selectReturns() []ast.Stmt {
+	sig := m.signature()
+	wait := &ast.SelectStmt{Body: &ast.BlockStmt{List: []ast.Stmt{m.receiveOutput(sig)}}}
+	always := m.alwaysCase(sig)
+	if always != nil {
+		wait.Body.List = append(wait.Body.List, always)
+	}
+	if m.guarded() {
+		wait.Body.List = append(wait.Body.List, m.emptyOutputCase(sig))
+	}
+	if m.receiver.settings.lifecycle {
+		wait.Body.List = append(wait.Body.List, m.closedCase())
+	}
+	ctx := m.contextParam(sig)
+	if ctx != "" {
+		wait.Body.List = append(wait.Body.List, m.contextCase(sig, ctx))
+	}
+	stmts := []ast.Stmt{
+		// Output that has already been queued is always returned,
+		// regardless of the policy, whether the mock is closed, or
+		// whether the context is done.
+		&ast.SelectStmt{Body: &ast.BlockStmt{List: []ast.Stmt{
+			m.receiveOutput(sig),
+			&ast.CommClause{},
+		}}},
+	}
+	if always != nil && len(wait.Body.List) > 2 {
+		// Values from the AlwaysReturns helper take precedence over
+		// everything else that the method may be waiting for.
+		stmts = append(stmts, &ast.SelectStmt{Body: &ast.BlockStmt{List: []ast.Stmt{
+			m.alwaysCase(sig),
+			&ast.CommClause{},
+		}}})
+	}
+	if m.implements.Results == nil || always != nil && len(wait.Body.List) == 2 {
+		// Either there is nothing to return, or every case returns.
+		return append(stmts, wait)
+	}
+	zeros, ret := m.zeroReturn(sig)
+	if ctx != "" {
+		// The context case sets the error result, so the zero values
+		// are declared before it.
+		return append(stmts, zeros, wait, ret)
+	}
+	return append(stmts, wait, zeros, ret)
+}
+
+// zeroReturn returns a declaration of variables holding the zero
+// values of m's results, and a statement which returns them.
+func (m Method) zeroReturn(sig signature) (*ast.DeclStmt, *ast.ReturnStmt) {
+	zeros := &ast.GenDecl{Tok: token.VAR}
+	ret := &ast.ReturnStmt{}
+	i := 0
+	for _, result := range sig.results {
+		n := len(result.Names)
+		if n == 0 {
+			n = 1
+		}
+		for j := 0; j < n; j++ {
+			zeros.Specs = append(zeros.Specs, &ast.ValueSpec{
+				Names: []*ast.Ident{{Name: sig.zeros[i]}},
+				Type:  result.Type,
+			})
+			ret.Results = append(ret.Results, &ast.Ident{Name: sig.zeros[i]})
+			i++
+		}
+	}
+	if len(zeros.Specs) > 1 {
+		zeros.Lparen = 1
+	}
+	return &ast.DeclStmt{Decl: zeros}, ret
+}
+
+// receiveOutput returns a select case which receives m's output and
+// returns it.
+func (m Method) receiveOutput(sig signature) *ast.CommClause {
+	recv := m.receiver.receiverName()
+	output := m.fieldNames().output
+	if m.implements.Results == nil {
+		// Only a blocking return, which is not returned.
+		ch := selectors(recv, output, sig.outputs[0].Names[0].Name)
+		if m.receiver.settings.records {
+			ch = selectors(recv, output)
+		}
+		return &ast.CommClause{
+			Comm: &ast.ExprStmt{X: &ast.UnaryExpr{Op: token.ARROW, X: ch}},
+			Body: []ast.Stmt{&ast.ReturnStmt{}},
+		}
+	}
+	ret := &ast.ReturnStmt{}
+	var first, ch ast.Expr
+	if m.receiver.settings.records {
+		first = &ast.Ident{Name: sig.record}
+		ch = selectors(recv, output)
+		for _, out := range sig.outputs {
+			for _, n := range out.Names {
+				ret.Results = append(ret.Results, selectors(sig.record, n.Name))
+			}
+		}
+	} else {
+		first = &ast.Ident{Name: sig.zeros[0]}
+		for _, out := range sig.outputs {
+			for _, n := range out.Names {
+				if ch == nil {
+					ch = selectors(recv, output, n.Name)
+					ret.Results = append(ret.Results, first)
+					continue
+				}
+				ret.Results = append(ret.Results, m.recvFrom(recv, output, n.Name))
+			}
+		}
+	}
+	return &ast.CommClause{
+		Comm: &ast.AssignStmt{
+			Lhs: []ast.Expr{first},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{&ast.UnaryExpr{Op: token.ARROW, X: ch}},
+		},
+		Body: []ast.Stmt{ret},
+	}
+}
+
+// alwaysCase returns a select case which receives the values that m's
+// AlwaysReturns helper hands out and returns them, or nil if m does not
+// have the helper.
+func (m Method) alwaysCase(sig signature) *ast.CommClause {
+	name := m.fieldNames().always
+	if name == "" {
+		return nil
+	}
+	ret := &ast.ReturnStmt{}
+	for _, out := range sig.outputs {
+		for _, n := range out.Names {
+			ret.Results = append(ret.Results, selectors(sig.record, n.Name))
+		}
+	}
+	return &ast.CommClause{
+		Comm: &ast.AssignStmt{
+			Lhs: []ast.Expr{&ast.Ident{Name: sig.record}},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{m.recvFrom(m.receiver.receiverName(), name)},
+		},
+		Body: []ast.Stmt{ret},
+	}
+}
+
+// recordReturns receives a single output record and returns the
+// values in it.
+func (m Method) recordReturns() []ast.Stmt {
+	recv := m.recvFrom(m.receiver.receiverName(), m.fieldNames().output)
+	if m.implements.Results == nil {
+		if !m.receiver.settings.blockingReturn {
+			return nil
+		}
+		return []ast.Stmt{&ast.ExprStmt{X: recv}}
+	}
+	sig := m.signature()
+	ret := &ast.ReturnStmt{}
+	for _, output := range sig.outputs {
+		for _, name := range output.Names {
+			ret.Results = append(ret.Results, selectors(sig.record, name.String()))
+		}
+	}
+	if len(ret.Results) == 0 {
+		return []ast.Stmt{ret}
+	}
+	return []ast.Stmt{
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{&ast.Ident{Name: sig.record}},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{recv},
+		},
+		ret,
+	}
+}
+
+// sideEffect calls m's side effect field, if it is set, with m's
+// arguments.
+func (m Method) sideEffect() ast.Stmt {
+	name := m.fieldNames().sideEffect
+	if name == "" {
+		return nil
+	}
+	field := selectors(m.receiver.receiverName(), name)
+	call := &ast.CallExpr{Fun: field}
+	for _, param := range m.signature().params {
+		for _, n := range param.Names {
+			call.Args = append(call.Args, &ast.Ident{Name: n.Name})
+		}
+		if _, ok := param.Type.(*ast.Ellipsis); ok {
+			call.Ellipsis = 1
+		}
+	}
+	return &ast.IfStmt{
+		Cond: &ast.BinaryExpr{X: field, Op: token.NEQ, Y: &ast.Ident{Name: "nil"}},
+		Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ExprStmt{X: call}}},
 	}
-	return &ast.ReturnStmt{Results: m.returnsExprs()}
 }
 
 func (m Method) body() *ast.BlockStmt {
-	stmts := []ast.Stmt{m.called()}
+	var stmts []ast.Stmt
+	if m.receiver.settings.verify {
+		stmts = append(stmts, m.track())
+	}
+	stmts = append(stmts, m.called())
 	stmts = append(stmts, m.inputs()...)
-	if returnStmt := m.returns(); returnStmt != nil {
-		stmts = append(stmts, m.returns())
+	if sideEffect := m.sideEffect(); sideEffect != nil {
+		stmts = append(stmts, sideEffect)
 	}
+	stmts = append(stmts, m.returns()...)
 	return &ast.BlockStmt{
 		List: stmts,
 	}
@@ -227,9 +227,9 @@ func TestMockMethodLocalTypes(t *testing.T) {
 	expected, err := format.Source([]byte(`
  package foo
 
- func (m *mockFoo) Foo(bar bar.Bar, baz func(f Foo) error) (*Foo, func() Foo, error) {
+ func (m *mockFoo) Foo(bar_ bar.Bar, baz func(f Foo) error) (*Foo, func() Foo, error) {
    m.FooCalled <- true
-   m.FooInput.Bar <- bar
+   m.FooInput.Bar <- bar_
    m.FooInput.Baz <- baz
    return <-m.FooOutput.Ret0, <-m.FooOutput.Ret1, <-m.FooOutput.Ret2
  }`))
@@ -243,9 +243,9 @@ func TestMockMethodLocalTypes(t *testing.T) {
 	expected, err = format.Source([]byte(`
  package foo
 
- func (m *mockFoo) Foo(bar bar.Bar, baz func(f foo.Foo) error) (*foo.Foo, func() foo.Foo, error) {
+ func (m *mockFoo) Foo(bar_ bar.Bar, baz func(f foo.Foo) error) (*foo.Foo, func() foo.Foo, error) {
    m.FooCalled <- true
-   m.FooInput.Bar <- bar
+   m.FooInput.Bar <- bar_
    m.FooInput.Baz <- baz
    return <-m.FooOutput.Ret0, <-m.FooOutput.Ret1, <-m.FooOutput.Ret2
  }`))
@@ -8,15 +8,65 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
-	"strings"
-	"unicode"
+	gotypes "go/types"
+	"io"
 )
 
 // Mock is a mock of an interface type.
 type Mock struct {
-	typeName       string
-	implements     *ast.InterfaceType
-	blockingReturn *bool
+	// name is the name that m's type name is based on.  It is usually
+	// the same as typeName, but may be altered to avoid conflicts.
+	name         string
+	typeName     string
+	implements   *ast.InterfaceType
+	interfaceDoc *ast.CommentGroup
+	settings     *settings
+
+	// dependency is true if m mocks an interface from another package,
+	// which one of the interfaces being mocked depends on.
+	dependency bool
+}
+
+// settings holds the values of a Mock that may be changed after it
+// is created.  Since Mock values are copied freely, they share a
+// pointer to their settings.
+type settings struct {
+	// pkg is the name of the package that the mocked interface type
+	// is declared in, when it is not the local package.
+	pkg            string
+	blockingReturn bool
+	records        bool
+	sideEffects    bool
+	unbounded      bool
+	verify         bool
+	lifecycle      bool
+	context        bool
+	style          string
+	spies          bool
+	helpers        bool
+	naming         *naming
+
+	// spy is true for the settings of a spy, rather than a mock.
+	spy bool
+
+	// emptyOutput is the empty output policy of methods which do not
+	// have their own (or their interface's) //hel:empty-output
+	// directive.
+	emptyOutput string
+
+	// chanSizes override the chan size passed to Constructor for some
+	// of the mock's channels.
+	chanSizes []ChanSize
+
+	// mockName and constructorName are the names allocated for the
+	// mock's type and constructor by Mocks, so that they are unique
+	// among all of the mocks that are generated together.
+	mockName, constructorName string
+
+	// spyName and spyConstructorName are the names allocated for the
+	// mock's spy type and constructor, like mockName and
+	// constructorName.
+	spyName, spyConstructorName string
 }
 
 // For returns a Mock representing typ.  An error will be returned
@@ -26,18 +76,91 @@ func For(typ *ast.TypeSpec) (Mock, error) {
 	if !ok {
 		return Mock{}, fmt.Errorf("TypeSpec.Type expected to be *ast.InterfaceType, was %T", typ.Type)
 	}
-	var blockingReturn bool
 	m := Mock{
-		typeName:       typ.Name.String(),
-		implements:     inter,
-		blockingReturn: &blockingReturn,
+		name:         typ.Name.String(),
+		typeName:     typ.Name.String(),
+		implements:   inter,
+		interfaceDoc: typ.Doc,
+		settings:     &settings{naming: defaultNaming},
+	}
+	if err := m.checkDirectives(); err != nil {
+		return Mock{}, err
 	}
 	return m, nil
 }
 
+// writeInputs writes everything that m's generated code depends on to
+// w, for hashing: its names, whether it is a dependency, its interface
+// definition (including the directives in its doc comments), and its
+// settings, with the style resolved for m and the blocking return, chan
+// sizes, and empty output policies (along with whether they can be
+// overridden) resolved for each method.
+func (m Mock) writeInputs(w io.Writer, chanSize int) {
+	s := *m.settings
+	s.naming, s.chanSizes, s.emptyOutput, s.style, s.blockingReturn = nil, nil, "", "", false
+	fmt.Fprintf(w, "mock %s %s %s %s %t\nsettings %#v\nnaming %#v\n", m.Interface(), m.Name(), m.ConstructorName(), m.style(), m.dependency, s, m.settings.naming.text)
+	writeComments(w, m.interfaceDoc)
+	for _, field := range m.implements.Methods.List {
+		writeComments(w, field.Doc)
+		for _, n := range field.Names {
+			fmt.Fprintf(w, "%s ", n.Name)
+		}
+		fmt.Fprintf(w, "%s\n", gotypes.ExprString(field.Type))
+	}
+	for _, method := range m.Methods() {
+		fmt.Fprintf(w, "method %s %t %d %d %q %t\n", method.name, method.hasOutputs(),
+			method.chanSize(ChanInput, chanSize), method.chanSize(ChanOutput, chanSize), method.emptyOutput(), method.guarded())
+	}
+}
+
+// writeComments writes the raw text of the comments in g to w.
+func writeComments(w io.Writer, g *ast.CommentGroup) {
+	if g == nil {
+		return
+	}
+	for _, c := range g.List {
+		fmt.Fprintf(w, "%s\n", c.Text)
+	}
+}
+
 // Name returns the type name for m.
 func (m Mock) Name() string {
-	return "mock" + strings.ToUpper(m.typeName[0:1]) + m.typeName[1:]
+	if m.settings.mockName != "" {
+		return m.settings.mockName
+	}
+	n := m.settings.naming
+	return n.name(n.typ, nameData{Interface: m.name})
+}
+
+// ConstructorName returns the name of m's constructor.
+func (m Mock) ConstructorName() string {
+	if m.settings.constructorName != "" {
+		return m.settings.constructorName
+	}
+	n := m.settings.naming
+	return n.name(n.constructor, nameData{Interface: m.name, Type: m.Name()})
+}
+
+func (m Mock) receiverName() string {
+	n := m.settings.naming
+	return n.name(n.receiver, nameData{Interface: m.name})
+}
+
+// BaseName returns the name that m's type name is based on.  It is
+// usually the name of the mocked interface type, but may be prefixed
+// with the name of the interface's package to keep it distinct from
+// other mocks.
+func (m Mock) BaseName() string {
+	return m.name
+}
+
+// Interface returns the name of the interface type that m mocks,
+// qualified by its package name if it is not in the local package.
+func (m Mock) Interface() string {
+	if m.settings.pkg == "" {
+		return m.typeName
+	}
+	return m.settings.pkg + "." + m.typeName
 }
 
 // Methods returns the methods that need to be created with m
@@ -46,16 +169,153 @@ func (m Mock) Methods() (methods []Method) {
 	for _, method := range m.implements.Methods.List {
 		switch methodType := method.Type.(type) {
 		case *ast.FuncType:
-			methods = append(methods, MethodFor(m, method.Names[0].String(), methodType))
+			newMethod := MethodFor(m, method.Names[0].String(), methodType)
+			newMethod.doc = method.Doc
+			methods = append(methods, newMethod)
 		}
 	}
 	return
 }
 
+// methodFields holds the names of a method's fields in its mock
+// struct.
+type methodFields struct {
+	called, input, output, sideEffect, emptyOutput string
+
+	// fn, calls, and history are the names of the func field, the call
+	// history method, and the field in the history struct of a method
+	// in a func style mock.
+	fn, calls, history string
+
+	// returns, alwaysReturns, and args are the names of the typed
+	// helper methods of a method.
+	returns, alwaysReturns, args string
+
+	// always is the name of the channel that a method's AlwaysReturns
+	// helper hands its values to calls on.
+	always string
+
+	// call is the name of the call queue field of a method in a
+	// runtime style mock.
+	call string
+}
+
+// mockFields holds the names of the fields and helper methods of a
+// mock, which share a single scope.
+type mockFields struct {
+	// methods holds the fields of each method, keyed by method name.
+	methods map[string]methodFields
+
+	// emptyOutput is the name of the helper method that implements
+	// empty output policies, if any of the mock's methods need it.
+	emptyOutput string
+
+	// backlog, enqueue, and flush are the names of the backlog field
+	// and the helper methods of an unbounded mock.
+	backlog, enqueue, flush string
+
+	// blocked, verify, track, and unconsumed are the names of the
+	// blocked field and the helper methods of a verified mock.
+	blocked, verify, track, unconsumed string
+
+	// closeMethod, reset, closed, closeOnce, and drain are the names of
+	// the lifecycle methods, fields, and helper method of a mock.
+	closeMethod, reset, closed, closeOnce, drain string
+
+	// history is the name of the call history field of a func style
+	// mock.
+	history string
+
+	// impl is the name of the field holding a spy's implementation.
+	impl string
+}
+
+// fields allocates the names of the fields and helper methods of m,
+// so that they conflict neither with each other nor with m's methods.
+func (m Mock) fields() mockFields {
+	n := m.settings.naming
+	methods := m.Methods()
+	s := newScope()
+	for _, method := range methods {
+		s.reserve(method.name)
+	}
+	if m.funcStyle() {
+		return m.funcFields(s, methods)
+	}
+	if m.runtimeStyle() {
+		return m.runtimeFields(s, methods)
+	}
+	fields := mockFields{methods: make(map[string]methodFields, len(methods))}
+	for _, method := range methods {
+		data := nameData{Method: method.name}
+		f := methodFields{called: s.name(n.name(n.called, data))}
+		if method.hasInputs() {
+			f.input = s.name(n.name(n.input, data))
+		}
+		if method.hasOutputs() {
+			output := n.output
+			if m.settings.spy {
+				output = n.results
+			}
+			f.output = s.name(n.name(output, data))
+		}
+		if m.settings.sideEffects {
+			f.sideEffect = s.name(n.name(n.sideEffect, data))
+		}
+		if method.guarded() {
+			f.emptyOutput = s.name(n.name(n.emptyOutput, data))
+		}
+		if m.settings.helpers {
+			if method.hasResults() && !m.settings.spy {
+				f.returns = s.name(n.name(n.returns, data))
+				f.alwaysReturns = s.name(n.name(n.alwaysReturns, data))
+				f.always = s.name("always" + method.name)
+			}
+			if method.hasInputs() {
+				f.args = s.name(n.name(n.args, data))
+			}
+		}
+		fields.methods[method.name] = f
+	}
+	for _, method := range methods {
+		if method.guarded() {
+			fields.emptyOutput = s.name("emptyOutput")
+			break
+		}
+	}
+	if m.settings.spy {
+		fields.impl = s.name("impl")
+	}
+	if m.settings.unbounded {
+		fields.backlog = s.name("backlog")
+		fields.enqueue = s.name("enqueue")
+		fields.flush = s.name("flush")
+	}
+	if m.settings.lifecycle {
+		// Close and Reset are exported, so they may conflict with the
+		// mocked interface's methods (e.g. io.Closer's Close).
+		fields.closeMethod = s.nameOr("Close", "CloseMock")
+		fields.reset = s.nameOr("Reset", "ResetMock")
+		fields.closed = s.name("closed")
+		fields.closeOnce = s.name("closeOnce")
+		fields.drain = s.name("drain")
+	}
+	if m.settings.verify {
+		fields.blocked = s.name("blocked")
+		fields.verify = s.name("verify")
+		fields.track = s.name("track")
+		fields.unconsumed = s.name("unconsumed")
+	}
+	return fields
+}
+
 // PrependLocalPackage prepends name as the package name for local types
 // in m's signature.  This is most often used when mocking types that are
 // imported by the local package.
 func (m Mock) PrependLocalPackage(name string) {
+	if m.settings.pkg == "" {
+		m.settings.pkg = name
+	}
 	for _, m := range m.Methods() {
 		m.PrependLocalPackage(name)
 	}
@@ -64,23 +324,153 @@ func (m Mock) PrependLocalPackage(name string) {
 // SetBlockingReturn sets whether or not methods will include a blocking
 // return channel, most often used for testing data races.
 func (m Mock) SetBlockingReturn(blockingReturn bool) {
-	*m.blockingReturn = blockingReturn
+	m.settings.blockingReturn = blockingReturn
+}
+
+// SetRecords sets whether or not methods send their arguments and
+// receive their return values as single record structs, rather than
+// on a channel per value.  Records keep the values of concurrent calls
+// from being interleaved.
+func (m Mock) SetRecords(records bool) {
+	m.settings.records = records
+}
+
+// SetSideEffects sets whether or not methods have a side effect field,
+// which (if it is set) is called with a method's arguments before
+// the method receives its return values.  Side effects allow mocks to
+// modify their arguments, e.g. to fill the buffer passed to a Read
+// method.
+func (m Mock) SetSideEffects(sideEffects bool) {
+	m.settings.sideEffects = sideEffects
+}
+
+// SetUnbounded sets whether or not m's methods send on its called and
+// input channels without blocking.  Values which do not fit in a
+// channel's buffer wait in a backlog until there is room for them, so
+// a mock may be called any number of times without its channels being
+// drained.
+func (m Mock) SetUnbounded(unbounded bool) {
+	m.settings.unbounded = unbounded
+}
+
+// SetVerify sets whether or not m's constructor takes a testing.TB,
+// which it uses to report the values that were never received from
+// m's channels, and the calls to m that were still blocked, when the
+// test finishes.  The generated code uses t.Cleanup, which requires
+// Go 1.14 or later.
+func (m Mock) SetVerify(verify bool) {
+	m.settings.verify = verify
+}
+
+// SetLifecycle sets whether or not m has lifecycle methods: Close,
+// which releases calls that are waiting for output (and makes later
+// calls return zero values rather than wait), and Reset, which drains
+// all of m's channels so that it may be reused.  They are named
+// CloseMock and ResetMock if the mocked interface has methods named
+// Close or Reset.
+func (m Mock) SetLifecycle(lifecycle bool) {
+	m.settings.lifecycle = lifecycle
+}
+
+// SetContext sets whether or not m's methods which take a
+// context.Context as their first parameter stop waiting for output
+// when the context is done, returning zero values along with the
+// context's error (in their last error result, if they have one).
+func (m Mock) SetContext(context bool) {
+	m.settings.context = context
+}
+
+// SetHelpers sets whether or not m has typed helper methods for each
+// of its methods: Returns queues one set of return values, AlwaysReturns
+// keeps returning the same values until it is stopped, and Args waits
+// for a call and returns its arguments.  Unlike pers, the helpers are
+// checked by the compiler.
+func (m Mock) SetHelpers(helpers bool) {
+	m.settings.helpers = helpers
+}
+
+// SetSpies sets whether or not a spy is generated along with m.  A spy
+// wraps an implementation of m's interface, forwarding each call to it.
+// Like m, it sends each call and its arguments on its called and input
+// channels, and it sends the implementation's results on its results
+// channels.  No spy is generated for an unexported interface mocked
+// from another package, since the spy's constructor would have to
+// refer to it.
+func (m Mock) SetSpies(spies bool) {
+	m.settings.spies = spies
+}
+
+// SetStyle sets the style of m (StyleChan, StyleFunc, or StyleRuntime),
+// unless its interface has a //hel:style directive.
+func (m Mock) SetStyle(style string) error {
+	if err := checkStyle(style); err != nil {
+		return err
+	}
+	m.settings.style = style
+	return nil
+}
+
+// SetEmptyOutput sets the policy for what m's methods do when they are
+// called and no output has been queued for them, for methods which do
+// not have an //hel:empty-output directive.  See EmptyOutputPolicies
+// for the valid policies.
+func (m Mock) SetEmptyOutput(policy string) error {
+	if err := checkEmptyOutput(policy); err != nil {
+		return err
+	}
+	m.settings.emptyOutput = policy
+	return nil
+}
+
+// SetChanSizes sets the overrides for the buffer sizes of m's
+// channels.  The most specific of sizes that applies to a channel is
+// used, and later sizes take precedence over earlier ones that are
+// just as specific.  //hel:chan-size directives (or their
+// input-chan-size and output-chan-size variants) on m's interface or
+// methods take precedence over all of sizes.
+func (m Mock) SetChanSizes(sizes []ChanSize) {
+	m.settings.chanSizes = sizes
+}
+
+// SetNaming sets the naming scheme used for the identifiers in m.  An
+// error will be returned if any of n's templates are invalid.
+func (m Mock) SetNaming(n Naming) error {
+	parsed, err := parseNaming(n)
+	if err != nil {
+		return err
+	}
+	m.settings.naming = parsed
+	m.settings.mockName, m.settings.constructorName = "", ""
+	return nil
 }
 
 // Constructor returns a function AST to construct m.  chanSize will be
-// the buffer size for all channels initialized in the constructor.
+// the buffer size for all channels initialized in the constructor,
+// unless it is overridden (see SetChanSizes).  If m is verified (see
+// SetVerify), the constructor takes a testing.TB.
 func (m Mock) Constructor(chanSize int) *ast.FuncDecl {
 	decl := &ast.FuncDecl{}
-	typeRunes := []rune(m.Name())
-	typeRunes[0] = unicode.ToUpper(typeRunes[0])
-	decl.Name = &ast.Ident{Name: "new" + string(typeRunes)}
+	decl.Name = &ast.Ident{Name: m.ConstructorName()}
 	decl.Type = &ast.FuncType{
+		Params: &ast.FieldList{},
 		Results: &ast.FieldList{List: []*ast.Field{{
 			Type: &ast.StarExpr{
 				X: &ast.Ident{Name: m.Name()},
 			},
 		}}},
 	}
+	if m.settings.verify {
+		decl.Type.Params.List = []*ast.Field{{
+			Names: []*ast.Ident{{Name: "t"}},
+			Type:  selectors("testing", "TB"),
+		}}
+	}
+	if m.settings.spy {
+		decl.Type.Params.List = []*ast.Field{{
+			Names: []*ast.Ident{{Name: "impl"}},
+			Type:  m.interfaceType(),
+		}}
+	}
 	decl.Body = &ast.BlockStmt{List: m.constructorBody(chanSize)}
 	return decl
 }
@@ -91,41 +481,136 @@ func (m Mock) Decl() *ast.GenDecl {
 	spec.Name = &ast.Ident{Name: m.Name()}
 	spec.Type = m.structType()
 	return &ast.GenDecl{
+		Doc:   m.doc(),
 		Tok:   token.TYPE,
 		Specs: []ast.Spec{spec},
 	}
 }
 
-// Ast returns all declaration AST for m.
+// assertable returns whether m's mock can assert that it implements
+// the interface type that it mocks.  Unexported interfaces can't be
+// referred to from other packages (e.g. foo_test), so they can't be
+// asserted on once m's local types are package-qualified.
+func (m Mock) assertable() bool {
+	return m.settings.pkg == "" || ast.IsExported(m.typeName)
+}
+
+// hasSpy returns whether a spy is generated along with m.
+func (m Mock) hasSpy() bool {
+	return m.settings.spies && !m.settings.spy && m.assertable()
+}
+
+// Assertion returns a spec which asserts that m implements the
+// interface type that it mocks, i.e. _ Foo = (*mockFoo)(nil).
+func (m Mock) Assertion() *ast.ValueSpec {
+	return &ast.ValueSpec{
+		Names: []*ast.Ident{{Name: "_"}},
+		Type:  m.interfaceType(),
+		Values: []ast.Expr{&ast.CallExpr{
+			Fun:  &ast.ParenExpr{X: &ast.StarExpr{X: &ast.Ident{Name: m.Name()}}},
+			Args: []ast.Expr{&ast.Ident{Name: "nil"}},
+		}},
+	}
+}
+
+// Ast returns all declaration AST for m, followed by its spy if
+// spies are enabled.
 func (m Mock) Ast(chanSize int) []ast.Decl {
+	decls := m.mockAst(chanSize)
+	if m.hasSpy() {
+		decls = append(decls, m.spy().Ast(chanSize)...)
+	}
+	return decls
+}
+
+// mockAst returns the declarations for m's type, constructor, and
+// methods, without its spy.
+func (m Mock) mockAst(chanSize int) []ast.Decl {
+	if m.funcStyle() {
+		return m.funcAst()
+	}
+	if m.runtimeStyle() {
+		return m.runtimeAst(chanSize)
+	}
 	decls := []ast.Decl{
 		m.Decl(),
 		m.Constructor(chanSize),
 	}
 	for _, method := range m.Methods() {
 		decls = append(decls, method.Ast())
+		decls = append(decls, method.helpers()...)
 	}
-	return decls
+	if helper := m.emptyOutputHelper(); helper != nil {
+		decls = append(decls, helper)
+	}
+	decls = append(decls, m.lifecycleMethods()...)
+	decls = append(decls, m.unboundedHelpers()...)
+	return append(decls, m.verifyHelpers()...)
 }
 
 func (m Mock) constructorBody(chanSize int) []ast.Stmt {
+	lit := &ast.CompositeLit{Type: &ast.Ident{Name: m.Name()}}
+	if m.settings.spy {
+		lit.Elts = []ast.Expr{&ast.KeyValueExpr{Key: &ast.Ident{Name: m.fields().impl}, Value: &ast.Ident{Name: "impl"}}}
+	}
 	structAlloc := &ast.AssignStmt{
 		Lhs: []ast.Expr{&ast.Ident{Name: "m"}},
 		Tok: token.DEFINE,
-		Rhs: []ast.Expr{&ast.UnaryExpr{Op: token.AND, X: &ast.CompositeLit{Type: &ast.Ident{Name: m.Name()}}}},
+		Rhs: []ast.Expr{&ast.UnaryExpr{Op: token.AND, X: lit}},
 	}
 	stmts := []ast.Stmt{structAlloc}
 	for _, method := range m.Methods() {
 		stmts = append(stmts, method.chanInit(chanSize)...)
 	}
+	if m.settings.lifecycle {
+		stmts = append(stmts, &ast.AssignStmt{
+			Lhs: []ast.Expr{selectors("m", m.fields().closed)},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{&ast.CallExpr{
+				Fun:  &ast.Ident{Name: "make"},
+				Args: []ast.Expr{&ast.ChanType{Dir: ast.SEND | ast.RECV, Value: emptyStruct()}},
+			}},
+		})
+	}
+	if m.settings.verify {
+		// t.Cleanup(func() { m.verify(t) })
+		stmts = append(stmts, &ast.ExprStmt{X: &ast.CallExpr{
+			Fun: selectors("t", "Cleanup"),
+			Args: []ast.Expr{&ast.FuncLit{
+				Type: &ast.FuncType{Params: &ast.FieldList{}},
+				Body: &ast.BlockStmt{List: []ast.Stmt{&ast.ExprStmt{X: &ast.CallExpr{
+					Fun:  selectors("m", m.fields().verify),
+					Args: []ast.Expr{&ast.Ident{Name: "t"}},
+				}}}},
+			}},
+		}})
+	}
 	stmts = append(stmts, &ast.ReturnStmt{Results: []ast.Expr{&ast.Ident{Name: "m"}}})
 	return stmts
 }
 
 func (m Mock) structType() *ast.StructType {
+	if m.funcStyle() {
+		return m.funcStructType()
+	}
+	if m.runtimeStyle() {
+		return m.runtimeStructType()
+	}
 	structType := &ast.StructType{Fields: &ast.FieldList{}}
 	for _, method := range m.Methods() {
 		structType.Fields.List = append(structType.Fields.List, method.Fields()...)
 	}
+	if m.settings.unbounded {
+		structType.Fields.List = append(structType.Fields.List, m.backlogField())
+	}
+	if m.settings.lifecycle {
+		structType.Fields.List = append(structType.Fields.List, m.lifecycleFields()...)
+	}
+	if m.settings.verify {
+		structType.Fields.List = append(structType.Fields.List, m.blockedField())
+	}
+	if m.settings.spy {
+		structType.Fields.List = append(structType.Fields.List, m.implField())
+	}
 	return structType
 }
@@ -48,6 +48,12 @@ func TestMockTypeDecl(t *testing.T) {
 	expected, err := format.Source([]byte(`
  package foo
 
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
  type mockFoo struct {
   FooCalled chan bool
   FooInput struct {
@@ -83,6 +89,12 @@ func TestMockTypeDecl(t *testing.T) {
 	expected, err = format.Source([]byte(`
  package foo
 
+ // mockFoo is a mock implementation of foo.Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
  type mockFoo struct {
   FooCalled chan bool
   FooInput struct {
@@ -118,6 +130,12 @@ func TestMockTypeDecl(t *testing.T) {
 	expected, err = format.Source([]byte(`
  package foo
 
+ // mockFoo is a mock implementation of foo.Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
  type mockFoo struct {
   FooCalled chan bool
   FooInput struct {
@@ -166,6 +184,12 @@ func TestMockTypeDecl_DirectionalChansGetParens(t *testing.T) {
 	expected, err := format.Source([]byte(`
  package foo
 
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
  type mockFoo struct {
   FooCalled chan bool
   FooInput struct {
@@ -196,6 +220,10 @@ type Foo interface {
 	expected, err := format.Source([]byte(`
  package foo
 
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput).
  type mockFoo struct {
   FooCalled chan bool
   FooInput struct {
@@ -222,7 +250,10 @@ type Foo interface {
 
 	expected, err := format.Source([]byte(`
 package foo
-
+// mockFoo is a mock implementation of Foo.
+//
+// Calling a method sends true on its called channel (e.g. FooCalled) and
+// each of its arguments on its input struct's channels (e.g. FooInput).
 type mockFoo struct {
  FooCalled chan bool
  FooInput struct {
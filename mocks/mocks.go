@@ -8,12 +8,14 @@ package mocks
 
 import (
 	"bytes"
+	"fmt"
 	"go/ast"
 	"go/format"
 	"go/parser"
 	"go/token"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -21,13 +23,6 @@ import (
 	"golang.org/x/tools/go/ast/astutil"
 )
 
-const commentHeader = `// This file was generated by github.com/nelsam/hel.  Do not
-// edit this code by hand unless you *really* know what you're
-// doing.  Expect any changes made manually to be overwritten
-// the next time hel regenerates this file.
-
-`
-
 // TypeFinder represents a type which knows about types and dependencies.
 type TypeFinder interface {
 	ExportedTypes() (types []*ast.TypeSpec)
@@ -40,12 +35,86 @@ type Mocks []Mock
 // Output writes the go code representing m to dest.  pkg will be the
 // package name; dir is the destination directory (needed for formatting
 // the file); chanSize is the buffer size of any channels created in
-// constructors.
-func (m Mocks) Output(pkg, dir string, chanSize int, dest io.Writer) error {
-	if _, err := dest.Write([]byte(commentHeader)); err != nil {
+// constructors.  opts may be used to add details to the header of the
+// output.
+//
+// The header of the output will include the value returned by
+// m.Hash(pkg, dir, chanSize, opts...).
+func (m Mocks) Output(pkg, dir string, chanSize int, dest io.Writer, opts ...OutputOption) error {
+	h, body, err := m.output(pkg, dir, chanSize, opts...)
+	if err != nil {
+		return err
+	}
+	if err := h.write(dest); err != nil {
 		return err
 	}
+	_, err = dest.Write(body)
+	return err
+}
+
+// Hash returns a hash of the interface definitions and generator
+// options that m would be output with, along with the version of hel
+// that would output it.  It is computed without generating any code,
+// so checking it saves rendering, formatting, and type checking m, but
+// not loading the package that m's interfaces were found in.  Options
+// are normalized (e.g. to the chan size that each method ends up
+// with), and the command line options recorded by WithArgs are not
+// included, so the hash only changes when the inputs or hel do.
+func (m Mocks) Hash(pkg, dir string, chanSize int, opts ...OutputOption) (string, error) {
+	imports, err := getImports(dir, token.NewFileSet())
+	if err != nil {
+		return "", err
+	}
+	var h Header
+	for _, opt := range opts {
+		opt(&h)
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "version %q\npackage %s\nlicense %q\nlayout %q\n", Version(), pkg, h.License, h.Layout)
+	specs := make([]string, 0, len(imports))
+	for _, spec := range imports {
+		name := ""
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		specs = append(specs, fmt.Sprintf("import %s %s\n", name, spec.Path.Value))
+	}
+	// The package's files are parsed in no particular order.
+	sort.Strings(specs)
+	for _, spec := range specs {
+		b.WriteString(spec)
+	}
+	for _, mock := range m {
+		mock.writeInputs(&b, chanSize)
+	}
+	return hash(b.Bytes()), nil
+}
 
+func (m Mocks) output(pkg, dir string, chanSize int, opts ...OutputOption) (Header, []byte, error) {
+	hash, err := m.Hash(pkg, dir, chanSize, opts...)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	body, err := m.render(pkg, dir, chanSize)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	h := Header{Version: Version(), Types: m.interfaces(), Hash: hash}
+	for _, opt := range opts {
+		opt(&h)
+	}
+	return h, body, nil
+}
+
+func (m Mocks) interfaces() []string {
+	names := make([]string, 0, len(m))
+	for _, mock := range m {
+		names = append(names, mock.Interface())
+	}
+	return names
+}
+
+func (m Mocks) render(pkg, dir string, chanSize int) ([]byte, error) {
 	fset := token.NewFileSet()
 
 	f := &ast.File{
@@ -59,17 +128,29 @@ func (m Mocks) Output(pkg, dir string, chanSize int, dest io.Writer) error {
 	// TODO: Determine why adding imports without creating a new ast file
 	// will only allow one import to be printed to the file.
 	fset = token.NewFileSet()
-	file, err := parser.ParseFile(fset, pkg, &b, 0)
+	file, err := parser.ParseFile(fset, pkg, &b, parser.ParseComments)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	file, fset, err = addImports(file, fset, dir)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	for _, mock := range m {
+		if mock.runtimeStyle() {
+			// The package's own files do not import helrt, and
+			// goimports may not be able to find it.
+			astutil.AddImport(fset, file, runtimePath)
+			break
+		}
 	}
 
-	return format.Node(dest, fset, file)
+	var out bytes.Buffer
+	if err := format.Node(&out, fset, file); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
 }
 
 // PrependLocalPackage prepends name as the package name for local types
@@ -89,13 +170,189 @@ func (m Mocks) SetBlockingReturn(blockingReturn bool) {
 	}
 }
 
+// SetRecords sets whether or not methods send their arguments and
+// receive their return values as single record structs, rather than
+// on a channel per value.  Records keep the values of concurrent calls
+// from being interleaved.
+func (m Mocks) SetRecords(records bool) {
+	for _, m := range m {
+		m.SetRecords(records)
+	}
+}
+
+// SetSideEffects sets whether or not methods have a side effect field,
+// which (if it is set) is called with a method's arguments before
+// the method receives its return values.  Side effects allow mocks to
+// modify their arguments, e.g. to fill the buffer passed to a Read
+// method.
+func (m Mocks) SetSideEffects(sideEffects bool) {
+	for _, m := range m {
+		m.SetSideEffects(sideEffects)
+	}
+}
+
+// SetUnbounded sets whether or not the methods of m send on their
+// called and input channels without blocking.  Values which do not fit
+// in a channel's buffer wait in a backlog until there is room for them.
+func (m Mocks) SetUnbounded(unbounded bool) {
+	for _, m := range m {
+		m.SetUnbounded(unbounded)
+	}
+}
+
+// SetContext sets whether or not the methods of m which take a
+// context.Context as their first parameter stop waiting for output
+// when the context is done.  See Mock.SetContext.
+func (m Mocks) SetContext(context bool) {
+	for _, m := range m {
+		m.SetContext(context)
+	}
+}
+
+// SetHelpers sets whether or not the mocks in m have typed helper
+// methods.  See Mock.SetHelpers.
+func (m Mocks) SetHelpers(helpers bool) {
+	for _, m := range m {
+		m.SetHelpers(helpers)
+	}
+}
+
+// SetLifecycle sets whether or not the mocks in m have Close and Reset
+// methods.  See Mock.SetLifecycle.
+func (m Mocks) SetLifecycle(lifecycle bool) {
+	for _, m := range m {
+		m.SetLifecycle(lifecycle)
+	}
+}
+
+// SetVerify sets whether or not the constructors of m take a
+// testing.TB, which they use to report the values that were never
+// received from their mocks' channels when the test finishes.  See
+// Mock.SetVerify.
+func (m Mocks) SetVerify(verify bool) {
+	for _, m := range m {
+		m.SetVerify(verify)
+	}
+}
+
+// SetChanSizes sets the overrides for the buffer sizes of the channels
+// of m.  See Mock.SetChanSizes.
+func (m Mocks) SetChanSizes(sizes []ChanSize) {
+	for _, m := range m {
+		m.SetChanSizes(sizes)
+	}
+}
+
+// SetSpies sets whether or not a spy is generated along with each of
+// the mocks in m.  See Mock.SetSpies.
+func (m Mocks) SetSpies(spies bool) {
+	for _, m := range m {
+		m.SetSpies(spies)
+	}
+	m.allocateNames()
+}
+
+// SetStyle sets the style of the mocks in m (StyleChan, StyleFunc, or
+// StyleRuntime), for mocks whose interfaces do not have a //hel:style
+// directive.
+func (m Mocks) SetStyle(style string) error {
+	if err := checkStyle(style); err != nil {
+		return err
+	}
+	for _, m := range m {
+		m.settings.style = style
+	}
+	return nil
+}
+
+// SetEmptyOutput sets the policy for what the methods of m do when
+// they are called and no output has been queued for them, for methods
+// which do not have an //hel:empty-output directive.  See
+// EmptyOutputPolicies for the valid policies.
+func (m Mocks) SetEmptyOutput(policy string) error {
+	if err := checkEmptyOutput(policy); err != nil {
+		return err
+	}
+	for _, m := range m {
+		m.settings.emptyOutput = policy
+	}
+	return nil
+}
+
+// SetNaming sets the naming scheme used for the identifiers in m.  An
+// error will be returned if any of n's templates are invalid.
+func (m Mocks) SetNaming(n Naming) error {
+	parsed, err := parseNaming(n)
+	if err != nil {
+		return err
+	}
+	for _, m := range m {
+		m.settings.naming = parsed
+	}
+	m.allocateNames()
+	return nil
+}
+
+// allocateNames allocates the names of the types and constructors in
+// m, so that they are unique among all of m's mocks.  For example,
+// mocks for Foo and foo would otherwise both be named mockFoo.
+func (m Mocks) allocateNames() {
+	for _, mock := range m {
+		mock.settings.mockName, mock.settings.constructorName = "", ""
+	}
+	s := newScope()
+	for _, mock := range m {
+		// Only names which had to change are stored, so that mocks
+		// without conflicts are left as they were.
+		if name := s.name(mock.Name()); name != mock.Name() {
+			mock.settings.mockName = name
+		}
+		if name := s.name(mock.ConstructorName()); name != mock.ConstructorName() {
+			mock.settings.constructorName = name
+		}
+	}
+	for _, mock := range m {
+		if !mock.hasSpy() {
+			continue
+		}
+		mock.settings.spyName, mock.settings.spyConstructorName = "", ""
+		if name := s.name(mock.spyName()); name != mock.spyName() {
+			mock.settings.spyName = name
+		}
+		if name := s.name(mock.spyConstructorName()); name != mock.spyConstructorName() {
+			mock.settings.spyConstructorName = name
+		}
+	}
+}
+
 func (m Mocks) decls(chanSize int) (decls []ast.Decl) {
+	if assertions := m.assertions(); len(assertions.Specs) > 0 {
+		decls = append(decls, assertions)
+	}
 	for _, mock := range m {
 		decls = append(decls, mock.Ast(chanSize)...)
 	}
 	return decls
 }
 
+// assertions returns a declaration which asserts that each mock in m
+// implements the interface that it mocks, so that changes to the
+// interfaces are caught when compiling the mocks.  Mocks which can't
+// refer to their interface are skipped.
+func (m Mocks) assertions() *ast.GenDecl {
+	decl := &ast.GenDecl{Tok: token.VAR}
+	for _, mock := range m {
+		if !mock.assertable() {
+			continue
+		}
+		decl.Specs = append(decl.Specs, mock.Assertion())
+		if mock.hasSpy() {
+			decl.Specs = append(decl.Specs, mock.spy().Assertion())
+		}
+	}
+	return decl
+}
+
 func addImports(file *ast.File, fset *token.FileSet, dirPath string) (*ast.File, *token.FileSet, error) {
 	imports, err := getImports(dirPath, fset)
 	if err != nil {
@@ -144,12 +401,14 @@ func Generate(finder TypeFinder) (Mocks, error) {
 	base := finder.ExportedTypes()
 	var (
 		typs []*ast.TypeSpec
-		deps []types.Dependency
+		deps []dependency
 	)
 	for _, typ := range base {
 		typs = append(typs, typ)
 		if inter, ok := typ.Type.(*ast.InterfaceType); ok {
-			deps = append(deps, finder.Dependencies(inter)...)
+			for _, dep := range finder.Dependencies(inter) {
+				deps = append(deps, dependency{Dependency: dep, name: dep.Type.Name.Name})
+			}
 		}
 	}
 	deps = deDupe(typs, deps)
@@ -166,16 +425,27 @@ func Generate(finder TypeFinder) (Mocks, error) {
 		if err != nil {
 			return nil, err
 		}
+		newMock.name = dep.name
+		newMock.dependency = true
 		newMock.PrependLocalPackage(dep.PkgName)
 		m = append(m, newMock)
 	}
+	m.allocateNames()
 	return m, nil
 }
 
-func deDupe(typs []*ast.TypeSpec, deps []types.Dependency) []types.Dependency {
+// dependency is a types.Dependency along with the name that its mock
+// will be named after, which may differ from the name of its type to
+// avoid conflicts.
+type dependency struct {
+	types.Dependency
+	name string
+}
+
+func deDupe(typs []*ast.TypeSpec, deps []dependency) []dependency {
 	for _, typ := range typs {
 		for i := 0; i < len(deps); i++ {
-			if deps[i].Type.Name.Name != typ.Name.Name {
+			if deps[i].name != typ.Name.Name {
 				continue
 			}
 			if deps[i].PkgName == "" {
@@ -183,7 +453,7 @@ func deDupe(typs []*ast.TypeSpec, deps []types.Dependency) []types.Dependency {
 				i--
 				continue
 			}
-			deps[i] = separate(deps[i], typ)
+			deps[i] = separate(deps[i], typ.Name.Name)
 		}
 	}
 	for i := 0; i < len(deps); i++ {
@@ -193,29 +463,29 @@ func deDupe(typs []*ast.TypeSpec, deps []types.Dependency) []types.Dependency {
 				j--
 				continue
 			}
-			deps[j] = separate(deps[j], deps[i].Type)
+			deps[j] = separate(deps[j], deps[i].name)
 		}
 	}
 	return deps
 }
 
-func equal(a, b types.Dependency) bool {
+func equal(a, b dependency) bool {
 	if a.PkgName != b.PkgName {
 		return false
 	}
-	if a.Type.Name.Name != b.Type.Name.Name {
+	if a.name != b.name {
 		return false
 	}
 	return true
 }
 
-func separate(dep types.Dependency, from *ast.TypeSpec) types.Dependency {
-	if dep.Type.Name.Name != from.Name.Name {
+func separate(dep dependency, from string) dependency {
+	if dep.name != from {
 		return dep
 	}
 	pkgTitle := strings.Title(dep.PkgName)
-	if !strings.HasSuffix(dep.Type.Name.Name, pkgTitle) {
-		dep.Type.Name.Name = pkgTitle + dep.Type.Name.Name
+	if !strings.HasSuffix(dep.name, pkgTitle) {
+		dep.name = pkgTitle + dep.name
 	}
 	return dep
 }
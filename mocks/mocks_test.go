@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"go/ast"
 	"go/format"
+	"strings"
 	"testing"
 
 	"github.com/a8m/expect"
@@ -66,13 +67,18 @@ func TestOutput(t *testing.T) {
 	m.Output("foo", "test/withoutimports", 100, &buf)
 
 	expected, err := format.Source([]byte(`
- // This file was generated by github.com/nelsam/hel.  Do not
- // edit this code by hand unless you *really* know what you're
- // doing.  Expect any changes made manually to be overwritten
- // the next time hel regenerates this file.
-
  package foo
 
+ var (
+  _ Foo = (*mockFoo)(nil)
+  _ Bar = (*mockBar)(nil)
+ )
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. BarCalled),
+ // then returns the values received from its output struct's channels
+ // (e.g. BarOutput).
  type mockFoo struct {
   BarCalled chan bool
   BarOutput struct {
@@ -91,6 +97,12 @@ func TestOutput(t *testing.T) {
   return <-m.BarOutput.Ret0
  }
 
+ // mockBar is a mock implementation of Bar.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
  type mockBar struct {
   FooCalled chan bool
   FooInput struct {
@@ -135,20 +147,25 @@ func TestOutput(t *testing.T) {
  }
  `))
 	expect(err).To.Be.Nil().Else.FailNow()
-	expect(buf.String()).To.Equal(string(expected))
+	expect(body(expect, buf.String())).To.Equal(string(expected))
 
 	m.PrependLocalPackage("foo")
 	buf = bytes.Buffer{}
 	m.Output("foo_test", "test/withoutimports", 100, &buf)
 
 	expected, err = format.Source([]byte(`
- // This file was generated by github.com/nelsam/hel.  Do not
- // edit this code by hand unless you *really* know what you're
- // doing.  Expect any changes made manually to be overwritten
- // the next time hel regenerates this file.
-
  package foo_test
 
+ var (
+  _ foo.Foo = (*mockFoo)(nil)
+  _ foo.Bar = (*mockBar)(nil)
+ )
+
+ // mockFoo is a mock implementation of foo.Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. BarCalled),
+ // then returns the values received from its output struct's channels
+ // (e.g. BarOutput).
  type mockFoo struct {
   BarCalled chan bool
   BarOutput struct {
@@ -167,6 +184,12 @@ func TestOutput(t *testing.T) {
   return <-m.BarOutput.Ret0
  }
 
+ // mockBar is a mock implementation of foo.Bar.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
  type mockBar struct {
   FooCalled chan bool
   FooInput struct {
@@ -196,9 +219,9 @@ func TestOutput(t *testing.T) {
   m.BaconOutput.Ret0 = make(chan func(foo.Eggs) foo.Eggs, 100)
   return m
  }
- func (m *mockBar) Foo(foo string) foo.Foo {
+ func (m *mockBar) Foo(foo_ string) foo.Foo {
   m.FooCalled <- true
-  m.FooInput.Foo <- foo
+  m.FooInput.Foo <- foo_
   return <-m.FooOutput.Ret0
  }
  func (m *mockBar) Baz() {
@@ -211,20 +234,25 @@ func TestOutput(t *testing.T) {
  }
  `))
 	expect(err).To.Be.Nil().Else.FailNow()
-	expect(buf.String()).To.Equal(string(expected))
+	expect(body(expect, buf.String())).To.Equal(string(expected))
 
 	m.SetBlockingReturn(true)
 	buf = bytes.Buffer{}
 	m.Output("foo_test", "test/withoutimports", 100, &buf)
 
 	expected, err = format.Source([]byte(`
- // This file was generated by github.com/nelsam/hel.  Do not
- // edit this code by hand unless you *really* know what you're
- // doing.  Expect any changes made manually to be overwritten
- // the next time hel regenerates this file.
-
  package foo_test
 
+ var (
+  _ foo.Foo = (*mockFoo)(nil)
+  _ foo.Bar = (*mockBar)(nil)
+ )
+
+ // mockFoo is a mock implementation of foo.Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. BarCalled),
+ // then returns the values received from its output struct's channels
+ // (e.g. BarOutput).
  type mockFoo struct {
   BarCalled chan bool
   BarOutput struct {
@@ -243,6 +271,12 @@ func TestOutput(t *testing.T) {
   return <-m.BarOutput.Ret0
  }
 
+ // mockBar is a mock implementation of foo.Bar.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
  type mockBar struct {
   FooCalled chan bool
   FooInput struct {
@@ -276,9 +310,9 @@ func TestOutput(t *testing.T) {
   m.BaconOutput.Ret0 = make(chan func(foo.Eggs) foo.Eggs, 100)
   return m
  }
- func (m *mockBar) Foo(foo string) foo.Foo {
+ func (m *mockBar) Foo(foo_ string) foo.Foo {
   m.FooCalled <- true
-  m.FooInput.Foo <- foo
+  m.FooInput.Foo <- foo_
   return <-m.FooOutput.Ret0
  }
  func (m *mockBar) Baz() {
@@ -292,7 +326,7 @@ func TestOutput(t *testing.T) {
  }
  `))
 	expect(err).To.Be.Nil().Else.FailNow()
-	expect(buf.String()).To.Equal(string(expected))
+	expect(body(expect, buf.String())).To.Equal(string(expected))
 }
 
 func TestOutput_Dependencies(t *testing.T) {
@@ -362,16 +396,35 @@ func TestOutput_Dependencies(t *testing.T) {
 	buf := bytes.Buffer{}
 	m.Output("foo", "test/withoutimports", 100, &buf)
 
+	h, ok, err := mocks.ReadHeader(bytes.NewReader(buf.Bytes()))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(ok).To.Be.Ok().Else.FailNow()
+	expect(h.Types).To.Equal([]string{"Bar", "Foo", "b.Foo", "baz.Baz"})
+
+	var baseNames []string
+	for _, mock := range m {
+		baseNames = append(baseNames, mock.BaseName())
+	}
+	expect(baseNames).To.Equal([]string{"Bar", "Foo", "BFoo", "Baz"})
+
 	// TODO: For some reason, functions are coming out without
 	// whitespace between them.  We need to figure that out.
 	expected, err := format.Source([]byte(`
- // This file was generated by github.com/nelsam/hel.  Do not
- // edit this code by hand unless you *really* know what you're
- // doing.  Expect any changes made manually to be overwritten
- // the next time hel regenerates this file.
-
  package foo
 
+ var (
+  _ Bar = (*mockBar)(nil)
+  _ Foo = (*mockFoo)(nil)
+  _ b.Foo = (*mockBFoo)(nil)
+  _ baz.Baz = (*mockBaz)(nil)
+ )
+
+ // mockBar is a mock implementation of Bar.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
  type mockBar struct {
   FooCalled chan bool
   FooInput struct {
@@ -397,6 +450,11 @@ func TestOutput_Dependencies(t *testing.T) {
   return <-m.FooOutput.Ret0, <-m.FooOutput.Ret1
  }
 
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
  type mockFoo struct {
   FooCalled chan bool
   FooOutput struct {
@@ -415,6 +473,11 @@ func TestOutput_Dependencies(t *testing.T) {
   return <-m.FooOutput.Ret0
  }
 
+ // mockBFoo is a mock implementation of b.Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
  type mockBFoo struct {
   FooCalled chan bool
   FooOutput struct {
@@ -433,6 +496,11 @@ func TestOutput_Dependencies(t *testing.T) {
   return <-m.FooOutput.Ret0
  }
 
+ // mockBaz is a mock implementation of baz.Baz.
+ //
+ // Calling a method sends true on its called channel (e.g. BazCalled),
+ // then returns the values received from its output struct's channels
+ // (e.g. BazOutput).
  type mockBaz struct {
   BazCalled chan bool
   BazOutput struct {
@@ -452,7 +520,7 @@ func TestOutput_Dependencies(t *testing.T) {
  }
  `))
 	expect(err).To.Be.Nil().Else.FailNow()
-	expect(buf.String()).To.Equal(string(expected))
+	expect(body(expect, buf.String())).To.Equal(string(expected))
 }
 
 func TestOutputWithPackageInputs(t *testing.T) {
@@ -481,11 +549,6 @@ func TestOutputWithPackageInputs(t *testing.T) {
 	m.Output("foo", "test/withimports", 100, &buf)
 
 	expected, err := format.Source([]byte(`
- // This file was generated by github.com/nelsam/hel.  Do not
- // edit this code by hand unless you *really* know what you're
- // doing.  Expect any changes made manually to be overwritten
- // the next time hel regenerates this file.
-
  package foo
 
  import (
@@ -493,6 +556,16 @@ func TestOutputWithPackageInputs(t *testing.T) {
 	"strconv"
  )
 
+ var (
+  _ Foo = (*mockFoo)(nil)
+  _ Bar = (*mockBar)(nil)
+ )
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. BarCalled),
+ // then returns the values received from its output struct's channels
+ // (e.g. BarOutput).
  type mockFoo struct {
   BarCalled chan bool
   BarOutput struct {
@@ -511,6 +584,12 @@ func TestOutputWithPackageInputs(t *testing.T) {
   return <-m.BarOutput.Ret0
  }
 
+ // mockBar is a mock implementation of Bar.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
  type mockBar struct {
   FooCalled chan bool
   FooInput struct {
@@ -555,7 +634,7 @@ func TestOutputWithPackageInputs(t *testing.T) {
  }
  `))
 	expect(err).To.Be.Nil().Else.FailNow()
-	expect(buf.String()).To.Equal(string(expected))
+	expect(body(expect, buf.String())).To.Equal(string(expected))
 }
 
 func TestOutput_ReceiverNameInArgs(t *testing.T) {
@@ -578,13 +657,14 @@ func TestOutput_ReceiverNameInArgs(t *testing.T) {
 	m.Output("foo", "test/withoutimports", 100, &buf)
 
 	expected, err := format.Source([]byte(`
- // This file was generated by github.com/nelsam/hel.  Do not
- // edit this code by hand unless you *really* know what you're
- // doing.  Expect any changes made manually to be overwritten
- // the next time hel regenerates this file.
-
  package foo
 
+ var _ Foo = (*mockFoo)(nil)
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput).
  type mockFoo struct {
    FooCalled chan bool
    FooInput struct {
@@ -604,7 +684,2278 @@ func TestOutput_ReceiverNameInArgs(t *testing.T) {
  }
  `))
 	expect(err).To.Be.Nil().Else.FailNow()
-	expect(buf.String()).To.Equal(string(expected))
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_PredeclaredNamesInArgs(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo(panic string, fmt int) (len int)
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	err = m.SetEmptyOutput(mocks.EmptyOutputPanic)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var _ Foo = (*mockFoo)(nil)
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput). If a method is called when no output is queued, it
+ // follows its empty output policy: "block" waits, "zero" returns zero
+ // values, "panic" panics, and a duration (e.g. "1s") panics if no output
+ // is queued within it. Setting a method's policy field (e.g.
+ // FooEmptyOutput) overrides the policy that it was generated with.
+ type mockFoo struct {
+  FooCalled chan bool
+  FooInput  struct {
+   Panic chan string
+   Fmt   chan int
+  }
+  FooOutput struct {
+   Len chan int
+  }
+  FooEmptyOutput string
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  m.FooInput.Panic = make(chan string, 100)
+  m.FooInput.Fmt = make(chan int, 100)
+  m.FooOutput.Len = make(chan int, 100)
+  return m
+ }
+ func (m *mockFoo) Foo(panic_ string, fmt_ int) (len_ int) {
+  m.FooCalled <- true
+  m.FooInput.Panic <- panic_
+  m.FooInput.Fmt <- fmt_
+  select {
+  case ret0 := <-m.FooOutput.Len:
+   return ret0
+  default:
+  }
+  select {
+  case ret0 := <-m.FooOutput.Len:
+   return ret0
+  case msg := <-m.emptyOutput(m.FooEmptyOutput, "panic", "Foo", panic_, fmt_):
+   if msg != "" {
+    panic(msg)
+   }
+  }
+  var ret0 int
+  return ret0
+ }
+
+ // emptyOutput returns a channel that the methods of mockFoo, when called
+ // with no output queued, select on along with their output. It follows
+ // policy, or fallback (the policy that the method was generated with) if
+ // policy is empty. The channel is closed if they should return zero
+ // values, or receives a message if they should panic. It panics straight
+ // away for the "panic" policy. The arguments are only formatted when the
+ // method panics.
+ func (m *mockFoo) emptyOutput(policy, fallback, method string, args ...interface{}) <-chan string {
+  if policy == "" {
+   policy = fallback
+  }
+  const msg = "mockFoo.%s was called with %v, but no output was queued"
+  done := make(chan string, 1)
+  switch policy {
+  case "block":
+   return nil
+  case "zero":
+   close(done)
+  case "panic":
+   panic(fmt.Sprintf(msg, method, args))
+  default:
+   timeout, err := time.ParseDuration(policy)
+   if err != nil {
+    panic(fmt.Sprintf("mockFoo.%s: invalid empty output policy %q", method, policy))
+   }
+   time.AfterFunc(timeout, func() {
+    done <- fmt.Sprintf(msg+" within %s", method, args, timeout)
+   })
+  }
+  return done
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_Collisions(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo()
+   FooCalled()
+   Bar(_ int, _ string) (_ error)
+   Baz(io io.Reader, true bool)
+  }`),
+		typeSpec(expect, `
+  type foo interface {
+   Foo()
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var (
+  _ Foo = (*mockFoo)(nil)
+  _ foo = (*mockFoo_)(nil)
+ )
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled_)
+ // and each of its arguments on its input struct's channels (e.g.
+ // BarInput), then returns the values received from its output struct's
+ // channels (e.g. BarOutput).
+ type mockFoo struct {
+   FooCalled_ chan bool ` + "`hel:\"Foo,called\"`" + `
+   FooCalledCalled chan bool
+   BarCalled chan bool
+   BarInput struct {
+     Arg0 chan int
+     Arg1 chan string
+   }
+   BarOutput struct {
+     Ret0 chan error
+   }
+   BazCalled chan bool
+   BazInput struct {
+     Io chan io.Reader
+     True chan bool
+   }
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled_ = make(chan bool, 100)
+  m.FooCalledCalled = make(chan bool, 100)
+  m.BarCalled = make(chan bool, 100)
+  m.BarInput.Arg0 = make(chan int, 100)
+  m.BarInput.Arg1 = make(chan string, 100)
+  m.BarOutput.Ret0 = make(chan error, 100)
+  m.BazCalled = make(chan bool, 100)
+  m.BazInput.Io = make(chan io.Reader, 100)
+  m.BazInput.True = make(chan bool, 100)
+  return m
+ }
+ func (m *mockFoo) Foo() {
+  m.FooCalled_ <- true
+ }
+ func (m *mockFoo) FooCalled() {
+  m.FooCalledCalled <- true
+ }
+ func (m *mockFoo) Bar(arg0 int, arg1 string) (_ error) {
+  m.BarCalled <- true
+  m.BarInput.Arg0 <- arg0
+  m.BarInput.Arg1 <- arg1
+  return <-m.BarOutput.Ret0
+ }
+ func (m *mockFoo) Baz(io_ io.Reader, true_ bool) {
+  m.BazCalled <- true
+  m.BazInput.Io <- io_
+  m.BazInput.True <- true_
+ }
+
+ // mockFoo_ is a mock implementation of foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled).
+ type mockFoo_ struct {
+   FooCalled chan bool
+ }
+
+ func newMockFoo_() *mockFoo_ {
+  m := &mockFoo_{}
+  m.FooCalled = make(chan bool, 100)
+  return m
+ }
+ func (m *mockFoo_) Foo() {
+  m.FooCalled <- true
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_Docs(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		documentedTypeSpec(expect, `
+  // Foo foos things.
+  //
+  // Deprecated: use Bar instead.
+  type Foo interface {
+   // Foo does the foo.
+   Foo(x int)
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var _ Foo = (*mockFoo)(nil)
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Foo foos things.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput).
+ type mockFoo struct {
+  FooCalled chan bool
+  FooInput struct {
+   X chan int
+  }
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  m.FooInput.X = make(chan int, 100)
+  return m
+ }
+
+ // Foo does the foo.
+ func (m *mockFoo) Foo(x int) {
+  m.FooCalled <- true
+  m.FooInput.X <- x
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_DependencyDocs(t *testing.T) {
+	expect := expect.New(t)
+
+	typs := []*ast.TypeSpec{
+		documentedTypeSpec(expect, `
+  // Foo foos things.
+  //
+  // It does so thoroughly.
+  type Foo interface {
+   // Foo does the foo.
+   //
+   // Thoroughly.
+   Foo() baz.Baz
+  }`),
+	}
+	deps := []types.Dependency{
+		{
+			PkgPath: "some/path/to/baz",
+			PkgName: "baz",
+			Type: documentedTypeSpec(expect, `
+  // A Baz bazzes.
+  //
+  // Bazzing is explained at length here.
+  type Baz interface {
+   // Baz bazzes.
+   //
+   // The details of bazzing are explained at length here.
+   Baz()
+  }`),
+		},
+	}
+
+	mockFinder := newMockTypeFinder()
+	mockFinder.ExportedTypesOutput.Types <- typs
+	mockFinder.DependenciesOutput.Dependencies <- deps
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var (
+  _ Foo     = (*mockFoo)(nil)
+  _ baz.Baz = (*mockBaz)(nil)
+ )
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Foo foos things.
+ //
+ // It does so thoroughly.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
+ type mockFoo struct {
+  FooCalled chan bool
+  FooOutput struct {
+   Ret0 chan baz.Baz
+  }
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  m.FooOutput.Ret0 = make(chan baz.Baz, 100)
+  return m
+ }
+
+ // Foo does the foo.
+ //
+ // Thoroughly.
+ func (m *mockFoo) Foo() baz.Baz {
+  m.FooCalled <- true
+  return <-m.FooOutput.Ret0
+ }
+
+ // mockBaz is a mock implementation of baz.Baz.
+ //
+ // A Baz bazzes.
+ //
+ // Calling a method sends true on its called channel (e.g. BazCalled).
+ type mockBaz struct {
+  BazCalled chan bool
+ }
+
+ func newMockBaz() *mockBaz {
+  m := &mockBaz{}
+  m.BazCalled = make(chan bool, 100)
+  return m
+ }
+
+ // Baz bazzes.
+ func (m *mockBaz) Baz() {
+  m.BazCalled <- true
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_Records(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo(out string, args ...int) (n int, err error)
+   Bar()
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.SetRecords(true)
+	m.SetBlockingReturn(true)
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var _ Foo = (*mockFoo)(nil)
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // a record of its arguments on its input channel (e.g. FooInput), then
+ // returns the values in the record received from its output channel
+ // (e.g. FooOutput).
+ type mockFoo struct {
+  FooCalled chan bool
+  FooInput  chan struct {
+   Out  string
+   Args []int
+  }
+  FooOutput chan struct {
+   N   int
+   Err error
+  }
+  BarCalled chan bool
+  BarOutput chan struct {
+   BlockReturn bool
+  }
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  m.FooInput = make(chan struct {
+   Out  string
+   Args []int
+  }, 100)
+  m.FooOutput = make(chan struct {
+   N   int
+   Err error
+  }, 100)
+  m.BarCalled = make(chan bool, 100)
+  m.BarOutput = make(chan struct {
+   BlockReturn bool
+  }, 100)
+  return m
+ }
+ func (m *mockFoo) Foo(out string, args ...int) (n int, err error) {
+  m.FooCalled <- true
+  m.FooInput <- struct {
+   Out  string
+   Args []int
+  }{out, args}
+  out_ := <-m.FooOutput
+  return out_.N, out_.Err
+ }
+ func (m *mockFoo) Bar() {
+  m.BarCalled <- true
+  <-m.BarOutput
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_SideEffects(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Read(p []byte) (int, error)
+   Bar(prefix string, args ...int)
+   Baz()
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.SetSideEffects(true)
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var _ Foo = (*mockFoo)(nil)
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. ReadCalled)
+ // and each of its arguments on its input struct's channels (e.g.
+ // ReadInput), then returns the values received from its output struct's
+ // channels (e.g. ReadOutput). If its side effect (e.g. ReadSideEffect)
+ // is set, it is called with the method's arguments before the return
+ // values are received.
+ type mockFoo struct {
+  ReadCalled chan bool
+  ReadInput  struct {
+   P chan []byte
+  }
+  ReadOutput struct {
+   Ret0 chan int
+   Ret1 chan error
+  }
+  ReadSideEffect func(p []byte)
+  BarCalled      chan bool
+  BarInput       struct {
+   Prefix chan string
+   Args   chan []int
+  }
+  BarSideEffect func(prefix string, args ...int)
+  BazCalled     chan bool
+  BazSideEffect func()
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.ReadCalled = make(chan bool, 100)
+  m.ReadInput.P = make(chan []byte, 100)
+  m.ReadOutput.Ret0 = make(chan int, 100)
+  m.ReadOutput.Ret1 = make(chan error, 100)
+  m.BarCalled = make(chan bool, 100)
+  m.BarInput.Prefix = make(chan string, 100)
+  m.BarInput.Args = make(chan []int, 100)
+  m.BazCalled = make(chan bool, 100)
+  return m
+ }
+ func (m *mockFoo) Read(p []byte) (int, error) {
+  m.ReadCalled <- true
+  m.ReadInput.P <- p
+  if m.ReadSideEffect != nil {
+   m.ReadSideEffect(p)
+  }
+  return <-m.ReadOutput.Ret0, <-m.ReadOutput.Ret1
+ }
+ func (m *mockFoo) Bar(prefix string, args ...int) {
+  m.BarCalled <- true
+  m.BarInput.Prefix <- prefix
+  m.BarInput.Args <- args
+  if m.BarSideEffect != nil {
+   m.BarSideEffect(prefix, args...)
+  }
+ }
+ func (m *mockFoo) Baz() {
+  m.BazCalled <- true
+  if m.BazSideEffect != nil {
+   m.BazSideEffect()
+  }
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_EmptyOutput(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		documentedTypeSpec(expect, `
+  type Foo interface {
+   Foo(msg string) (int, error)
+   //hel:empty-output 1s
+   Bar() (ret0 string)
+   Baz()
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	err = m.SetEmptyOutput(mocks.EmptyOutputZero)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var _ Foo = (*mockFoo)(nil)
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput). If a method is called when no output is queued, it
+ // follows its empty output policy: "block" waits, "zero" returns zero
+ // values, "panic" panics, and a duration (e.g. "1s") panics if no output
+ // is queued within it. Setting a method's policy field (e.g.
+ // FooEmptyOutput) overrides the policy that it was generated with.
+ type mockFoo struct {
+  FooCalled chan bool
+  FooInput  struct {
+   Msg chan string
+  }
+  FooOutput struct {
+   Ret0 chan int
+   Ret1 chan error
+  }
+  FooEmptyOutput string
+  BarCalled      chan bool
+  BarOutput      struct {
+   Ret0 chan string
+  }
+  BarEmptyOutput string
+  BazCalled      chan bool
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  m.FooInput.Msg = make(chan string, 100)
+  m.FooOutput.Ret0 = make(chan int, 100)
+  m.FooOutput.Ret1 = make(chan error, 100)
+  m.BarCalled = make(chan bool, 100)
+  m.BarOutput.Ret0 = make(chan string, 100)
+  m.BazCalled = make(chan bool, 100)
+  return m
+ }
+ func (m *mockFoo) Foo(msg string) (int, error) {
+  m.FooCalled <- true
+  m.FooInput.Msg <- msg
+  select {
+  case ret0 := <-m.FooOutput.Ret0:
+   return ret0, <-m.FooOutput.Ret1
+  default:
+  }
+  select {
+  case ret0 := <-m.FooOutput.Ret0:
+   return ret0, <-m.FooOutput.Ret1
+  case msg_ := <-m.emptyOutput(m.FooEmptyOutput, "zero", "Foo", msg):
+   if msg_ != "" {
+    panic(msg_)
+   }
+  }
+  var (
+   ret0 int
+   ret1 error
+  )
+  return ret0, ret1
+ }
+ func (m *mockFoo) Bar() (ret0 string) {
+  m.BarCalled <- true
+  select {
+  case ret0_ := <-m.BarOutput.Ret0:
+   return ret0_
+  default:
+  }
+  select {
+  case ret0_ := <-m.BarOutput.Ret0:
+   return ret0_
+  case msg := <-m.emptyOutput(m.BarEmptyOutput, "1s", "Bar"):
+   if msg != "" {
+    panic(msg)
+   }
+  }
+  var ret0_ string
+  return ret0_
+ }
+ func (m *mockFoo) Baz() {
+  m.BazCalled <- true
+ }
+
+ // emptyOutput returns a channel that the methods of mockFoo, when called
+ // with no output queued, select on along with their output. It follows
+ // policy, or fallback (the policy that the method was generated with) if
+ // policy is empty. The channel is closed if they should return zero
+ // values, or receives a message if they should panic. It panics straight
+ // away for the "panic" policy. The arguments are only formatted when the
+ // method panics.
+ func (m *mockFoo) emptyOutput(policy, fallback, method string, args ...interface{}) <-chan string {
+  if policy == "" {
+   policy = fallback
+  }
+  const msg = "mockFoo.%s was called with %v, but no output was queued"
+  done := make(chan string, 1)
+  switch policy {
+  case "block":
+   return nil
+  case "zero":
+   close(done)
+  case "panic":
+   panic(fmt.Sprintf(msg, method, args))
+  default:
+   timeout, err := time.ParseDuration(policy)
+   if err != nil {
+    panic(fmt.Sprintf("mockFoo.%s: invalid empty output policy %q", method, policy))
+   }
+   time.AfterFunc(timeout, func() {
+    done <- fmt.Sprintf(msg+" within %s", method, args, timeout)
+   })
+  }
+  return done
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestEmptyOutput_Invalid(t *testing.T) {
+	expect := expect.New(t)
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- []*ast.TypeSpec{typeSpec(expect, "type Foo interface { Foo() int }")}
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	for _, policy := range []string{"", "sometimes", "-1s"} {
+		err := m.SetEmptyOutput(policy)
+		expect(err).Not.To.Be.Nil()
+	}
+
+	mockFinder = newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- []*ast.TypeSpec{documentedTypeSpec(expect, `
+  type Foo interface {
+   //hel:empty-output never
+   Foo() int
+  }`)}
+	_, err = mocks.Generate(mockFinder)
+	expect(err).Not.To.Be.Nil().Else.FailNow()
+	expect(strings.Contains(err.Error(), "Foo.Foo")).To.Be.Ok()
+}
+
+func TestOutput_Unbounded(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo(x int) string
+   Bar()
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.SetUnbounded(true)
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var _ Foo = (*mockFoo)(nil)
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput). Sending never blocks: values which do not fit in a
+ // channel wait in a backlog until there is room for them.
+ type mockFoo struct {
+  FooCalled chan bool
+  FooInput  struct {
+   X chan int
+  }
+  FooOutput struct {
+   Ret0 chan string
+  }
+  BarCalled chan bool
+  backlog   struct {
+   sync.Mutex
+   values map[interface{}][]reflect.Value
+  }
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  m.FooInput.X = make(chan int, 100)
+  m.FooOutput.Ret0 = make(chan string, 100)
+  m.BarCalled = make(chan bool, 100)
+  return m
+ }
+ func (m *mockFoo) Foo(x int) string {
+  m.enqueue(m.FooCalled, true)
+  m.enqueue(m.FooInput.X, x)
+  return <-m.FooOutput.Ret0
+ }
+ func (m *mockFoo) Bar() {
+  m.enqueue(m.BarCalled, true)
+ }
+
+ // enqueue sends v on ch, which is one of mockFoo's channels, without
+ // blocking. If ch is full, or earlier values are still waiting to be
+ // sent on it, v is added to the backlog and sent by flush.
+ func (m *mockFoo) enqueue(ch, v interface{}) {
+  c := reflect.ValueOf(ch)
+  value := reflect.ValueOf(v)
+  if v == nil {
+   value = reflect.Zero(c.Type().Elem())
+  }
+  m.backlog.Lock()
+  defer m.backlog.Unlock()
+  pending, flushing := m.backlog.values[ch]
+  if !flushing && c.TrySend(value) {
+   return
+  }
+  if m.backlog.values == nil {
+   m.backlog.values = make(map[interface{}][]reflect.Value)
+  }
+  m.backlog.values[ch] = append(pending, value)
+  if !flushing {
+   go m.flush(c)
+  }
+ }
+
+ // flush sends the backlog of values for c, in order, until it is empty.
+ func (m *mockFoo) flush(c reflect.Value) {
+  ch := c.Interface()
+  m.backlog.Lock()
+  defer m.backlog.Unlock()
+  for len(m.backlog.values[ch]) > 0 {
+   value := m.backlog.values[ch][0]
+   m.backlog.Unlock()
+   c.Send(value)
+   m.backlog.Lock()
+   m.backlog.values[ch] = m.backlog.values[ch][1:]
+  }
+  delete(m.backlog.values, ch)
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_ChanSizes(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		documentedTypeSpec(expect, `
+  //hel:output-chan-size 1
+  type Foo interface {
+   //hel:chan-size 0
+   Foo(x int) string
+   Bar(y int) string
+   Baz(z int) string
+  }`),
+		typeSpec(expect, `
+  type Logger interface {
+   Write(p []byte) (int, error)
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.SetChanSizes([]mocks.ChanSize{
+		{Interface: "Logger", Method: "Write", Kind: mocks.ChanInput, Size: 5000},
+		{Kind: mocks.ChanInput, Size: 10},
+		{Interface: "Foo", Method: "Baz", Size: 20},
+		{Interface: "Logger", Size: 30},
+	})
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var (
+  _ Foo    = (*mockFoo)(nil)
+  _ Logger = (*mockLogger)(nil)
+ )
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
+ type mockFoo struct {
+  FooCalled chan bool
+  FooInput  struct {
+   X chan int
+  }
+  FooOutput struct {
+   Ret0 chan string
+  }
+  BarCalled chan bool
+  BarInput  struct {
+   Y chan int
+  }
+  BarOutput struct {
+   Ret0 chan string
+  }
+  BazCalled chan bool
+  BazInput  struct {
+   Z chan int
+  }
+  BazOutput struct {
+   Ret0 chan string
+  }
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 0)
+  m.FooInput.X = make(chan int, 0)
+  m.FooOutput.Ret0 = make(chan string, 0)
+  m.BarCalled = make(chan bool, 10)
+  m.BarInput.Y = make(chan int, 10)
+  m.BarOutput.Ret0 = make(chan string, 1)
+  m.BazCalled = make(chan bool, 20)
+  m.BazInput.Z = make(chan int, 20)
+  m.BazOutput.Ret0 = make(chan string, 1)
+  return m
+ }
+ func (m *mockFoo) Foo(x int) string {
+  m.FooCalled <- true
+  m.FooInput.X <- x
+  return <-m.FooOutput.Ret0
+ }
+ func (m *mockFoo) Bar(y int) string {
+  m.BarCalled <- true
+  m.BarInput.Y <- y
+  return <-m.BarOutput.Ret0
+ }
+ func (m *mockFoo) Baz(z int) string {
+  m.BazCalled <- true
+  m.BazInput.Z <- z
+  return <-m.BazOutput.Ret0
+ }
+
+ // mockLogger is a mock implementation of Logger.
+ //
+ // Calling a method sends true on its called channel (e.g. WriteCalled)
+ // and each of its arguments on its input struct's channels (e.g.
+ // WriteInput), then returns the values received from its output struct's
+ // channels (e.g. WriteOutput).
+ type mockLogger struct {
+  WriteCalled chan bool
+  WriteInput  struct {
+   P chan []byte
+  }
+  WriteOutput struct {
+   Ret0 chan int
+   Ret1 chan error
+  }
+ }
+
+ func newMockLogger() *mockLogger {
+  m := &mockLogger{}
+  m.WriteCalled = make(chan bool, 5000)
+  m.WriteInput.P = make(chan []byte, 5000)
+  m.WriteOutput.Ret0 = make(chan int, 30)
+  m.WriteOutput.Ret1 = make(chan error, 30)
+  return m
+ }
+ func (m *mockLogger) Write(p []byte) (int, error) {
+  m.WriteCalled <- true
+  m.WriteInput.P <- p
+  return <-m.WriteOutput.Ret0, <-m.WriteOutput.Ret1
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestParseChanSize(t *testing.T) {
+	expect := expect.New(t)
+
+	for _, test := range []struct {
+		value    string
+		expected mocks.ChanSize
+	}{
+		{"Logger=10", mocks.ChanSize{Interface: "Logger", Size: 10}},
+		{"Logger.Write=5000", mocks.ChanSize{Interface: "Logger", Method: "Write", Size: 5000}},
+		{"Logger.Write:input=0", mocks.ChanSize{Interface: "Logger", Method: "Write", Kind: mocks.ChanInput, Size: 0}},
+		{"*:output=1", mocks.ChanSize{Kind: mocks.ChanOutput, Size: 1}},
+	} {
+		size, err := mocks.ParseChanSize(test.value)
+		expect(err).To.Be.Nil().Else.FailNow()
+		expect(size).To.Equal(test.expected)
+	}
+
+	for _, value := range []string{"Logger", "Logger=-1", "Logger=big", "Logger:both=1", "=1", "Logger.=1"} {
+		_, err := mocks.ParseChanSize(value)
+		expect(err).Not.To.Be.Nil()
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- []*ast.TypeSpec{documentedTypeSpec(expect, `
+  //hel:input-chan-size lots
+  type Foo interface {
+   Foo() int
+  }`)}
+	_, err := mocks.Generate(mockFinder)
+	expect(err).Not.To.Be.Nil()
+}
+
+func TestOutput_Verify(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo(x int, y ...string) error
+   Bar()
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.SetVerify(true)
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var _ Foo = (*mockFoo)(nil)
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput). newMockFoo registers a cleanup with the test which
+ // reports calls that were not asserted on, output that was not returned,
+ // and calls that are still blocked (using t.Cleanup, which requires Go
+ // 1.14 or later).
+ type mockFoo struct {
+  FooCalled chan bool
+  FooInput  struct {
+   X chan int
+   Y chan []string
+  }
+  FooOutput struct {
+   Ret0 chan error
+  }
+  BarCalled chan bool
+  blocked   struct {
+   sync.Mutex
+   next  int
+   calls map[int]string
+  }
+ }
+
+ func newMockFoo(t testing.TB) *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  m.FooInput.X = make(chan int, 100)
+  m.FooInput.Y = make(chan []string, 100)
+  m.FooOutput.Ret0 = make(chan error, 100)
+  m.BarCalled = make(chan bool, 100)
+  t.Cleanup(func() {
+   m.verify(t)
+  })
+  return m
+ }
+ func (m *mockFoo) Foo(x int, y ...string) error {
+  defer m.track("Foo", x, y)()
+  m.FooCalled <- true
+  m.FooInput.X <- x
+  m.FooInput.Y <- y
+  return <-m.FooOutput.Ret0
+ }
+ func (m *mockFoo) Bar() {
+  defer m.track("Bar")()
+  m.BarCalled <- true
+ }
+
+ // verify reports calls to mockFoo that were not asserted on, output that
+ // was queued but not returned, and calls that are still blocked.
+ // newMockFoo registers it to run when the test finishes.
+ func (m *mockFoo) verify(t testing.TB) {
+  t.Helper()
+  for _, call := range m.unconsumed(m.FooCalled, m.FooInput.X, m.FooInput.Y) {
+   t.Errorf("mockFoo.Foo was called with %v, but the call was not asserted on", call[1:])
+  }
+  for _, output := range m.unconsumed(m.FooOutput.Ret0) {
+   t.Errorf("mockFoo.Foo had output %v queued, but it was not returned", output)
+  }
+  for _, call := range m.unconsumed(m.BarCalled) {
+   t.Errorf("mockFoo.Bar was called with %v, but the call was not asserted on", call[1:])
+  }
+  m.blocked.Lock()
+  defer m.blocked.Unlock()
+  for _, call := range m.blocked.calls {
+   t.Errorf("mockFoo.%s, but the call was still blocked when the test finished", call)
+  }
+ }
+
+ // track records a call to mockFoo, returning a func which removes it
+ // once the call returns.
+ func (m *mockFoo) track(method string, args ...interface{}) func() {
+  m.blocked.Lock()
+  defer m.blocked.Unlock()
+  if m.blocked.calls == nil {
+   m.blocked.calls = make(map[int]string)
+  }
+  id := m.blocked.next
+  m.blocked.next++
+  m.blocked.calls[id] = fmt.Sprintf("%s was called with %v", method, args)
+  return func() {
+   m.blocked.Lock()
+   defer m.blocked.Unlock()
+   delete(m.blocked.calls, id)
+  }
+ }
+
+ // unconsumed receives the values left in chans, which are sent on
+ // together, returning a slice of values for each time that the first of
+ // them was sent on.
+ func (m *mockFoo) unconsumed(chans ...interface{}) (values [][]interface{}) {
+  for {
+   var round []interface{}
+   for i, ch := range chans {
+    v, ok := reflect.ValueOf(ch).TryRecv()
+    if !ok {
+     if i == 0 {
+      return values
+     }
+     continue
+    }
+    round = append(round, v.Interface())
+   }
+   values = append(values, round)
+  }
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_Lifecycle(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo(x int) (string, error)
+   Close() error
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.SetLifecycle(true)
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var _ Foo = (*mockFoo)(nil)
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput). CloseMock releases calls that are waiting for
+ // output, and Reset drains all of the channels.
+ type mockFoo struct {
+  FooCalled chan bool
+  FooInput  struct {
+   X chan int
+  }
+  FooOutput struct {
+   Ret0 chan string
+   Ret1 chan error
+  }
+  CloseCalled chan bool
+  CloseOutput struct {
+   Ret0 chan error
+  }
+  closed    chan struct{}
+  closeOnce sync.Once
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  m.FooInput.X = make(chan int, 100)
+  m.FooOutput.Ret0 = make(chan string, 100)
+  m.FooOutput.Ret1 = make(chan error, 100)
+  m.CloseCalled = make(chan bool, 100)
+  m.CloseOutput.Ret0 = make(chan error, 100)
+  m.closed = make(chan struct{})
+  return m
+ }
+ func (m *mockFoo) Foo(x int) (string, error) {
+  select {
+  case m.FooCalled <- true:
+  case <-m.closed:
+  }
+  select {
+  case m.FooInput.X <- x:
+  case <-m.closed:
+  }
+  select {
+  case ret0 := <-m.FooOutput.Ret0:
+   return ret0, <-m.FooOutput.Ret1
+  default:
+  }
+  select {
+  case ret0 := <-m.FooOutput.Ret0:
+   return ret0, <-m.FooOutput.Ret1
+  case <-m.closed:
+  }
+  var (
+   ret0 string
+   ret1 error
+  )
+  return ret0, ret1
+ }
+ func (m *mockFoo) Close() error {
+  select {
+  case m.CloseCalled <- true:
+  case <-m.closed:
+  }
+  select {
+  case ret0 := <-m.CloseOutput.Ret0:
+   return ret0
+  default:
+  }
+  select {
+  case ret0 := <-m.CloseOutput.Ret0:
+   return ret0
+  case <-m.closed:
+  }
+  var ret0 error
+  return ret0
+ }
+
+ // CloseMock releases calls to mockFoo that are waiting for output, which
+ // return zero values. Calls made after CloseMock return output that has
+ // already been queued, or zero values, and may not be sent on their
+ // called and input channels.
+ func (m *mockFoo) CloseMock() {
+  m.closeOnce.Do(func() {
+   close(m.closed)
+  })
+ }
+
+ // Reset drains all of mockFoo's channels, so that it may be reused (e.g.
+ // by subtests).
+ func (m *mockFoo) Reset() {
+  m.drain(m.FooCalled, m.FooInput.X, m.FooOutput.Ret0, m.FooOutput.Ret1, m.CloseCalled, m.CloseOutput.Ret0)
+ }
+
+ // drain receives the values left in chans.
+ func (m *mockFoo) drain(chans ...interface{}) {
+  for _, ch := range chans {
+   c := reflect.ValueOf(ch)
+   for {
+    if _, ok := c.TryRecv(); !ok {
+     break
+    }
+   }
+  }
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_Context(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo(ctx context.Context, x int) (string, error)
+   Bar(context.Context) int
+   Baz(x int) error
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.SetContext(true)
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var _ Foo = (*mockFoo)(nil)
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput). If a method's context is done before its output is
+ // received, it returns zero values and the context's error.
+ type mockFoo struct {
+  FooCalled chan bool
+  FooInput  struct {
+   Ctx chan context.Context
+   X   chan int
+  }
+  FooOutput struct {
+   Ret0 chan string
+   Ret1 chan error
+  }
+  BarCalled chan bool
+  BarInput  struct {
+   Arg0 chan context.Context
+  }
+  BarOutput struct {
+   Ret0 chan int
+  }
+  BazCalled chan bool
+  BazInput  struct {
+   X chan int
+  }
+  BazOutput struct {
+   Ret0 chan error
+  }
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  m.FooInput.Ctx = make(chan context.Context, 100)
+  m.FooInput.X = make(chan int, 100)
+  m.FooOutput.Ret0 = make(chan string, 100)
+  m.FooOutput.Ret1 = make(chan error, 100)
+  m.BarCalled = make(chan bool, 100)
+  m.BarInput.Arg0 = make(chan context.Context, 100)
+  m.BarOutput.Ret0 = make(chan int, 100)
+  m.BazCalled = make(chan bool, 100)
+  m.BazInput.X = make(chan int, 100)
+  m.BazOutput.Ret0 = make(chan error, 100)
+  return m
+ }
+ func (m *mockFoo) Foo(ctx context.Context, x int) (string, error) {
+  m.FooCalled <- true
+  m.FooInput.Ctx <- ctx
+  m.FooInput.X <- x
+  select {
+  case ret0 := <-m.FooOutput.Ret0:
+   return ret0, <-m.FooOutput.Ret1
+  default:
+  }
+  var (
+   ret0 string
+   ret1 error
+  )
+  select {
+  case ret0 := <-m.FooOutput.Ret0:
+   return ret0, <-m.FooOutput.Ret1
+  case <-ctx.Done():
+   ret1 = ctx.Err()
+  }
+  return ret0, ret1
+ }
+ func (m *mockFoo) Bar(arg0 context.Context) int {
+  m.BarCalled <- true
+  m.BarInput.Arg0 <- arg0
+  select {
+  case ret0 := <-m.BarOutput.Ret0:
+   return ret0
+  default:
+  }
+  var ret0 int
+  select {
+  case ret0 := <-m.BarOutput.Ret0:
+   return ret0
+  case <-arg0.Done():
+  }
+  return ret0
+ }
+ func (m *mockFoo) Baz(x int) error {
+  m.BazCalled <- true
+  m.BazInput.X <- x
+  return <-m.BazOutput.Ret0
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_FuncStyle(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		documentedTypeSpec(expect, `
+  //hel:style func
+  type Foo interface {
+   Foo(x int, y ...string) (string, error)
+   //hel:empty-output zero
+   Bar()
+  }`),
+		typeSpec(expect, `
+  type Baz interface {
+   Baz()
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var (
+  _ Foo = (*mockFoo)(nil)
+  _ Baz = (*mockBaz)(nil)
+ )
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method records its arguments in its call history (e.g.
+ // FooCalls()) and then calls its func field (e.g. FooFunc). If the func
+ // field is not set, the method returns zero values or panics, depending
+ // on its empty output policy.
+ type mockFoo struct {
+  FooFunc func(x int, y ...string) (string, error)
+  BarFunc func()
+  history struct {
+   sync.Mutex
+   Foo []struct {
+    X int
+    Y []string
+   }
+   Bar []struct{}
+  }
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  return m
+ }
+ func (m *mockFoo) Foo(x int, y ...string) (string, error) {
+  m.history.Lock()
+  m.history.Foo = append(m.history.Foo, struct {
+   X int
+   Y []string
+  }{x, y})
+  m.history.Unlock()
+  if m.FooFunc == nil {
+   panic(fmt.Sprintf("mockFoo.Foo was called with %v, but FooFunc is not set", []interface{}{x, y}))
+  }
+  return m.FooFunc(x, y...)
+ }
+
+ // FooCalls returns the arguments of each call to Foo, in order.
+ func (m *mockFoo) FooCalls() []struct {
+  X int
+  Y []string
+ } {
+  m.history.Lock()
+  defer m.history.Unlock()
+  return append([]struct {
+   X int
+   Y []string
+  }(nil), m.history.Foo...)
+ }
+ func (m *mockFoo) Bar() {
+  m.history.Lock()
+  m.history.Bar = append(m.history.Bar, struct{}{})
+  m.history.Unlock()
+  if m.BarFunc == nil {
+   return
+  }
+  m.BarFunc()
+ }
+
+ // BarCalls returns the arguments of each call to Bar, in order.
+ func (m *mockFoo) BarCalls() []struct{} {
+  m.history.Lock()
+  defer m.history.Unlock()
+  return append([]struct{}(nil), m.history.Bar...)
+ }
+
+ // mockBaz is a mock implementation of Baz.
+ //
+ // Calling a method sends true on its called channel (e.g. BazCalled).
+ type mockBaz struct {
+  BazCalled chan bool
+ }
+
+ func newMockBaz() *mockBaz {
+  m := &mockBaz{}
+  m.BazCalled = make(chan bool, 100)
+  return m
+ }
+ func (m *mockBaz) Baz() {
+  m.BazCalled <- true
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+
+	err = m.SetStyle("funcs")
+	expect(err).Not.To.Be.Nil()
+}
+
+func TestOutput_Spies(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo(x int, y ...string) (string, error)
+   Bar()
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.SetSpies(true)
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var (
+  _ Foo = (*mockFoo)(nil)
+  _ Foo = (*spyFoo)(nil)
+ )
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
+ type mockFoo struct {
+  FooCalled chan bool
+  FooInput  struct {
+   X chan int
+   Y chan []string
+  }
+  FooOutput struct {
+   Ret0 chan string
+   Ret1 chan error
+  }
+  BarCalled chan bool
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  m.FooInput.X = make(chan int, 100)
+  m.FooInput.Y = make(chan []string, 100)
+  m.FooOutput.Ret0 = make(chan string, 100)
+  m.FooOutput.Ret1 = make(chan error, 100)
+  m.BarCalled = make(chan bool, 100)
+  return m
+ }
+ func (m *mockFoo) Foo(x int, y ...string) (string, error) {
+  m.FooCalled <- true
+  m.FooInput.X <- x
+  m.FooInput.Y <- y
+  return <-m.FooOutput.Ret0, <-m.FooOutput.Ret1
+ }
+ func (m *mockFoo) Bar() {
+  m.BarCalled <- true
+ }
+
+ // spyFoo is a spy on an implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // its arguments on its input channels (e.g. FooInput), then forwards the
+ // call to the implementation passed to newSpyFoo and sends the values it
+ // returns on its results channels (e.g. FooResults) before returning
+ // them.
+ type spyFoo struct {
+  FooCalled chan bool
+  FooInput  struct {
+   X chan int
+   Y chan []string
+  }
+  FooResults struct {
+   Ret0 chan string
+   Ret1 chan error
+  }
+  BarCalled chan bool
+  impl      Foo
+ }
+
+ func newSpyFoo(impl Foo) *spyFoo {
+  m := &spyFoo{impl: impl}
+  m.FooCalled = make(chan bool, 100)
+  m.FooInput.X = make(chan int, 100)
+  m.FooInput.Y = make(chan []string, 100)
+  m.FooResults.Ret0 = make(chan string, 100)
+  m.FooResults.Ret1 = make(chan error, 100)
+  m.BarCalled = make(chan bool, 100)
+  return m
+ }
+ func (m *spyFoo) Foo(x int, y ...string) (string, error) {
+  m.FooCalled <- true
+  m.FooInput.X <- x
+  m.FooInput.Y <- y
+  ret0, ret1 := m.impl.Foo(x, y...)
+  m.FooResults.Ret0 <- ret0
+  m.FooResults.Ret1 <- ret1
+  return ret0, ret1
+ }
+ func (m *spyFoo) Bar() {
+  m.BarCalled <- true
+  m.impl.Bar()
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_Helpers(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo(x int, y ...string) (s string, err error)
+   Bar() int
+   Baz(m map[string]int)
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.SetHelpers(true)
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var _ Foo = (*mockFoo)(nil)
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // each of its arguments on its input struct's channels (e.g. FooInput),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput). Typed helpers queue return values (e.g. FooReturns
+ // and FooAlwaysReturns) and wait for calls and return their arguments
+ // (e.g. FooArgs).
+ type mockFoo struct {
+  FooCalled chan bool
+  FooInput  struct {
+   X chan int
+   Y chan []string
+  }
+  FooOutput struct {
+   S   chan string
+   Err chan error
+  }
+  alwaysFoo chan struct {
+   S   string
+   Err error
+  }
+  BarCalled chan bool
+  BarOutput struct {
+   Ret0 chan int
+  }
+  alwaysBar chan struct {
+   Ret0 int
+  }
+  BazCalled chan bool
+  BazInput  struct {
+   M chan map[string]int
+  }
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  m.FooInput.X = make(chan int, 100)
+  m.FooInput.Y = make(chan []string, 100)
+  m.FooOutput.S = make(chan string, 100)
+  m.FooOutput.Err = make(chan error, 100)
+  m.alwaysFoo = make(chan struct {
+   S   string
+   Err error
+  })
+  m.BarCalled = make(chan bool, 100)
+  m.BarOutput.Ret0 = make(chan int, 100)
+  m.alwaysBar = make(chan struct {
+   Ret0 int
+  })
+  m.BazCalled = make(chan bool, 100)
+  m.BazInput.M = make(chan map[string]int, 100)
+  return m
+ }
+ func (m *mockFoo) Foo(x int, y ...string) (s string, err error) {
+  m.FooCalled <- true
+  m.FooInput.X <- x
+  m.FooInput.Y <- y
+  select {
+  case ret0 := <-m.FooOutput.S:
+   return ret0, <-m.FooOutput.Err
+  default:
+  }
+  select {
+  case ret0 := <-m.FooOutput.S:
+   return ret0, <-m.FooOutput.Err
+  case out := <-m.alwaysFoo:
+   return out.S, out.Err
+  }
+ }
+
+ // FooReturns queues one set of return values for a call to Foo.
+ func (m *mockFoo) FooReturns(s string, err error) {
+  m.FooOutput.S <- s
+  m.FooOutput.Err <- err
+ }
+
+ // FooAlwaysReturns keeps returning the same values from calls to Foo
+ // which have no other output queued, until stop is called. Values are
+ // only handed to calls as they are made, so none are left over after
+ // stop is called.
+ func (m *mockFoo) FooAlwaysReturns(s string, err error) (stop func()) {
+  done, exited := make(chan struct{}), make(chan struct{})
+  go func() {
+   defer close(exited)
+   for {
+    select {
+    case m.alwaysFoo <- struct {
+     S   string
+     Err error
+    }{s, err}:
+    case <-done:
+     return
+    }
+   }
+  }()
+  return func() {
+   close(done)
+   <-exited
+  }
+ }
+
+ // FooArgs waits for a call to Foo and returns its arguments.
+ func (m *mockFoo) FooArgs() (x int, y []string) {
+  <-m.FooCalled
+  return <-m.FooInput.X, <-m.FooInput.Y
+ }
+ func (m *mockFoo) Bar() int {
+  m.BarCalled <- true
+  select {
+  case ret0 := <-m.BarOutput.Ret0:
+   return ret0
+  default:
+  }
+  select {
+  case ret0 := <-m.BarOutput.Ret0:
+   return ret0
+  case out := <-m.alwaysBar:
+   return out.Ret0
+  }
+ }
+
+ // BarReturns queues one set of return values for a call to Bar.
+ func (m *mockFoo) BarReturns(ret0 int) {
+  m.BarOutput.Ret0 <- ret0
+ }
+
+ // BarAlwaysReturns keeps returning the same values from calls to Bar
+ // which have no other output queued, until stop is called. Values are
+ // only handed to calls as they are made, so none are left over after
+ // stop is called.
+ func (m *mockFoo) BarAlwaysReturns(ret0 int) (stop func()) {
+  done, exited := make(chan struct{}), make(chan struct{})
+  go func() {
+   defer close(exited)
+   for {
+    select {
+    case m.alwaysBar <- struct {
+     Ret0 int
+    }{ret0}:
+    case <-done:
+     return
+    }
+   }
+  }()
+  return func() {
+   close(done)
+   <-exited
+  }
+ }
+ func (m *mockFoo) Baz(m_ map[string]int) {
+  m.BazCalled <- true
+  m.BazInput.M <- m_
+ }
+
+ // BazArgs waits for a call to Baz and returns its arguments.
+ func (m *mockFoo) BazArgs() (m_ map[string]int) {
+  <-m.BazCalled
+  return <-m.BazInput.M
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_HelpersRecords(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo(in int, y ...string) (string, error)
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.SetHelpers(true)
+	m.SetRecords(true)
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var _ Foo = (*mockFoo)(nil)
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled) and
+ // a record of its arguments on its input channel (e.g. FooInput), then
+ // returns the values in the record received from its output channel
+ // (e.g. FooOutput). Typed helpers queue return values (e.g. FooReturns
+ // and FooAlwaysReturns) and wait for calls and return their arguments
+ // (e.g. FooArgs).
+ type mockFoo struct {
+  FooCalled chan bool
+  FooInput  chan struct {
+   In int
+   Y  []string
+  }
+  FooOutput chan struct {
+   Ret0 string
+   Ret1 error
+  }
+  alwaysFoo chan struct {
+   Ret0 string
+   Ret1 error
+  }
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  m.FooInput = make(chan struct {
+   In int
+   Y  []string
+  }, 100)
+  m.FooOutput = make(chan struct {
+   Ret0 string
+   Ret1 error
+  }, 100)
+  m.alwaysFoo = make(chan struct {
+   Ret0 string
+   Ret1 error
+  })
+  return m
+ }
+ func (m *mockFoo) Foo(in int, y ...string) (string, error) {
+  m.FooCalled <- true
+  m.FooInput <- struct {
+   In int
+   Y  []string
+  }{in, y}
+  select {
+  case out := <-m.FooOutput:
+   return out.Ret0, out.Ret1
+  default:
+  }
+  select {
+  case out := <-m.FooOutput:
+   return out.Ret0, out.Ret1
+  case out := <-m.alwaysFoo:
+   return out.Ret0, out.Ret1
+  }
+ }
+
+ // FooReturns queues one set of return values for a call to Foo.
+ func (m *mockFoo) FooReturns(ret0 string, ret1 error) {
+  m.FooOutput <- struct {
+   Ret0 string
+   Ret1 error
+  }{ret0, ret1}
+ }
+
+ // FooAlwaysReturns keeps returning the same values from calls to Foo
+ // which have no other output queued, until stop is called. Values are
+ // only handed to calls as they are made, so none are left over after
+ // stop is called.
+ func (m *mockFoo) FooAlwaysReturns(ret0 string, ret1 error) (stop func()) {
+  done, exited := make(chan struct{}), make(chan struct{})
+  go func() {
+   defer close(exited)
+   for {
+    select {
+    case m.alwaysFoo <- struct {
+     Ret0 string
+     Ret1 error
+    }{ret0, ret1}:
+    case <-done:
+     return
+    }
+   }
+  }()
+  return func() {
+   close(done)
+   <-exited
+  }
+ }
+
+ // FooArgs waits for a call to Foo and returns its arguments.
+ func (m *mockFoo) FooArgs() (in int, y []string) {
+  <-m.FooCalled
+  in_ := <-m.FooInput
+  return in_.In, in_.Y
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_RuntimeStyle(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo(x int, y ...string) (s string, err error)
+   Bar()
+  }`),
+		documentedTypeSpec(expect, `
+  //hel:style chan
+  type Baz interface {
+   Baz()
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	err = m.SetStyle(mocks.StyleRuntime)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.SetHelpers(true)
+	m.SetChanSizes([]mocks.ChanSize{{Interface: "Foo", Method: "Foo", Kind: mocks.ChanOutput, Size: 1}})
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ import "github.com/nelsam/hel/helrt"
+
+ var (
+  _ Foo = (*mockFoo)(nil)
+  _ Baz = (*mockBaz)(nil)
+ )
+
+ // mockFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends its arguments to its call queue (e.g. FooCall)
+ // and, if it has results, returns the next results queued with the call
+ // queue's Return or AlwaysReturn methods. pers works with the call
+ // queues as it does with channels. Typed helpers queue return values
+ // (e.g. FooReturns and FooAlwaysReturns) and wait for calls and return
+ // their arguments (e.g. FooArgs).
+ type mockFoo struct {
+  FooCall helrt.Call[struct {
+   X int
+   Y []string
+  }, struct {
+   S   string
+   Err error
+  }]
+  BarCall helrt.Call[struct{}, struct{}]
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCall.Init("Foo", 100, 1)
+  m.BarCall.Init("Bar", 100, 100)
+  return m
+ }
+ func (m *mockFoo) Foo(x int, y ...string) (s string, err error) {
+  out := m.FooCall.Invoke(struct {
+   X int
+   Y []string
+  }{x, y})
+  return out.S, out.Err
+ }
+
+ // FooReturns queues one set of return values for a call to Foo.
+ func (m *mockFoo) FooReturns(s string, err error) {
+  m.FooCall.Return(struct {
+   S   string
+   Err error
+  }{s, err})
+ }
+
+ // FooAlwaysReturns keeps returning the same values from calls to Foo
+ // which have no other output queued, until stop is called. Values are
+ // only handed to calls as they are made, so none are left over after
+ // stop is called.
+ func (m *mockFoo) FooAlwaysReturns(s string, err error) (stop func()) {
+  return m.FooCall.AlwaysReturn(struct {
+   S   string
+   Err error
+  }{s, err})
+ }
+
+ // FooArgs waits for a call to Foo and returns its arguments.
+ func (m *mockFoo) FooArgs() (x int, y []string) {
+  in := m.FooCall.Args()
+  return in.X, in.Y
+ }
+ func (m *mockFoo) Bar() {
+  m.BarCall.Record(struct{}{})
+ }
+
+ // mockBaz is a mock implementation of Baz.
+ //
+ // Calling a method sends true on its called channel (e.g. BazCalled).
+ type mockBaz struct {
+  BazCalled chan bool
+ }
+
+ func newMockBaz() *mockBaz {
+  m := &mockBaz{}
+  m.BazCalled = make(chan bool, 100)
+  return m
+ }
+ func (m *mockBaz) Baz() {
+  m.BazCalled <- true
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_UnexportedInPackage(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo()
+  }`),
+		typeSpec(expect, `
+  type doer interface {
+   Do()
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.PrependLocalPackage("foo")
+	m.SetSpies(true)
+
+	buf := bytes.Buffer{}
+	m.Output("foo_test", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo_test
+
+ var (
+  _ foo.Foo = (*mockFoo)(nil)
+  _ foo.Foo = (*spyFoo)(nil)
+ )
+
+ // mockFoo is a mock implementation of foo.Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled).
+ type mockFoo struct {
+  FooCalled chan bool
+ }
+
+ func newMockFoo() *mockFoo {
+  m := &mockFoo{}
+  m.FooCalled = make(chan bool, 100)
+  return m
+ }
+ func (m *mockFoo) Foo() {
+  m.FooCalled <- true
+ }
+
+ // spyFoo is a spy on an implementation of foo.Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalled),
+ // then forwards the call to the implementation passed to newSpyFoo.
+ type spyFoo struct {
+  FooCalled chan bool
+  impl      foo.Foo
+ }
+
+ func newSpyFoo(impl foo.Foo) *spyFoo {
+  m := &spyFoo{impl: impl}
+  m.FooCalled = make(chan bool, 100)
+  return m
+ }
+ func (m *spyFoo) Foo() {
+  m.FooCalled <- true
+  m.impl.Foo()
+ }
+
+ // mockDoer is a mock implementation of foo.doer.
+ //
+ // Calling a method sends true on its called channel (e.g. DoCalled).
+ type mockDoer struct {
+  DoCalled chan bool
+ }
+
+ func newMockDoer() *mockDoer {
+  m := &mockDoer{}
+  m.DoCalled = make(chan bool, 100)
+  return m
+ }
+ func (m *mockDoer) Do() {
+  m.DoCalled <- true
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestOutput_Naming(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo(f string) error
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	err = m.SetNaming(mocks.Naming{
+		Type:        "fake{{.Interface}}",
+		Constructor: "New{{title .Type}}",
+		Called:      "{{.Method}}Calls",
+		Input:       "{{.Method}}Args",
+		Receiver:    "{{lower .Interface | printf \"%.1s\"}}",
+	})
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	buf := bytes.Buffer{}
+	m.Output("foo", "test/withoutimports", 100, &buf)
+
+	expected, err := format.Source([]byte(`
+ package foo
+
+ var _ Foo = (*fakeFoo)(nil)
+
+ // fakeFoo is a mock implementation of Foo.
+ //
+ // Calling a method sends true on its called channel (e.g. FooCalls) and
+ // each of its arguments on its input struct's channels (e.g. FooArgs),
+ // then returns the values received from its output struct's channels
+ // (e.g. FooOutput).
+ type fakeFoo struct {
+   FooCalls chan bool ` + "`hel:\"Foo,called\"`" + `
+   FooArgs struct {
+     F chan string
+   } ` + "`hel:\"Foo,input\"`" + `
+   FooOutput struct {
+     Ret0 chan error
+   } ` + "`hel:\"Foo,output\"`" + `
+ }
+
+ func NewFakeFoo() *fakeFoo {
+  m := &fakeFoo{}
+  m.FooCalls = make(chan bool, 100)
+  m.FooArgs.F = make(chan string, 100)
+  m.FooOutput.Ret0 = make(chan error, 100)
+  return m
+ }
+ func (f *fakeFoo) Foo(f_ string) error {
+  f.FooCalls <- true
+  f.FooArgs.F <- f_
+  return <-f.FooOutput.Ret0
+ }
+ `))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(body(expect, buf.String())).To.Equal(string(expected))
+}
+
+func TestSetNaming_Invalid(t *testing.T) {
+	expect := expect.New(t)
+
+	types := []*ast.TypeSpec{
+		typeSpec(expect, `
+  type Foo interface {
+   Foo()
+  }`),
+	}
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- types
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+
+	expect(m.SetNaming(mocks.Naming{Type: "{{.Interface"})).Not.To.Be.Nil()
+	expect(m.SetNaming(mocks.Naming{Type: "{{.Nope}}"})).Not.To.Be.Nil()
+	expect(m.SetNaming(mocks.Naming{Type: "mock-{{.Interface}}"})).Not.To.Be.Nil()
+	expect(m.SetNaming(mocks.Naming{Type: "fake{{.Type}}"})).Not.To.Be.Nil()
+	expect(m.SetNaming(mocks.Naming{Called: "{{.Interface}}Called"})).Not.To.Be.Nil()
+	expect(m.SetNaming(mocks.Naming{Constructor: "make{{.Type}}"})).To.Be.Nil()
+	expect(m.SetNaming(mocks.Naming{Type: "mock_{{snake .Interface}}"})).To.Be.Nil()
+	expect(m[0].Name()).To.Equal("mock_foo")
 }
 
 func mockFor(expect func(interface{}) *expect.Expect, spec *ast.TypeSpec) mocks.Mock {
@@ -0,0 +1,228 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// NameFuncs are the functions available to naming templates.
+var NameFuncs = template.FuncMap{
+	"snake": snake,
+	"lower": strings.ToLower,
+	"title": title,
+}
+
+// Naming holds the text/templates used to name the identifiers in
+// generated mocks.  Empty fields use the default from DefaultNaming.
+//
+// Type, Spy, and Receiver are executed with {{.Interface}}, the name
+// of the mocked interface.  Constructor is executed with {{.Interface}}
+// and {{.Type}}, the name of the mock (or spy) type.  Called, Input,
+// Output, SideEffect, EmptyOutput, Func, Calls, Results, Returns,
+// AlwaysReturns, Args, and Call are executed with {{.Method}}, the name
+// of the mocked method.
+type Naming struct {
+	Type          string
+	Constructor   string
+	Called        string
+	Input         string
+	Output        string
+	SideEffect    string
+	EmptyOutput   string
+	Func          string
+	Calls         string
+	Spy           string
+	Results       string
+	Returns       string
+	AlwaysReturns string
+	Args          string
+	Call          string
+	Receiver      string
+}
+
+// DefaultNaming is the naming scheme that hel uses unless told
+// otherwise.
+var DefaultNaming = Naming{
+	Type:          "mock{{title .Interface}}",
+	Constructor:   "new{{title .Type}}",
+	Called:        "{{.Method}}Called",
+	Input:         "{{.Method}}Input",
+	Output:        "{{.Method}}Output",
+	SideEffect:    "{{.Method}}SideEffect",
+	EmptyOutput:   "{{.Method}}EmptyOutput",
+	Func:          "{{.Method}}Func",
+	Calls:         "{{.Method}}Calls",
+	Spy:           "spy{{title .Interface}}",
+	Results:       "{{.Method}}Results",
+	Returns:       "{{.Method}}Returns",
+	AlwaysReturns: "{{.Method}}AlwaysReturns",
+	Args:          "{{.Method}}Args",
+	Call:          "{{.Method}}Call",
+	Receiver:      "m",
+}
+
+// nameData is the data that naming templates are executed with.
+type nameData struct {
+	Interface string
+	Type      string
+	Method    string
+}
+
+// naming is a parsed Naming.
+type naming struct {
+	typ, constructor, called, input, output, sideEffect, emptyOutput      *template.Template
+	fn, calls, spy, results, returns, alwaysReturns, args, call, receiver *template.Template
+
+	// tagged is true when the names of the fields that pers looks
+	// for differ from the defaults, meaning that the fields need
+	// struct tags so that pers can find them.
+	tagged bool
+
+	// text is the Naming that was parsed, with the defaults filled in.
+	text Naming
+}
+
+var defaultNaming = mustParseNaming(DefaultNaming)
+
+// Validate returns an error if any of the templates in n can't be
+// parsed or executed with the variables that it is documented to be
+// executed with, or if it doesn't produce a valid identifier.
+func (n Naming) Validate() error {
+	_, err := parseNaming(n)
+	return err
+}
+
+func mustParseNaming(n Naming) *naming {
+	parsed, err := parseNaming(n)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func parseNaming(n Naming) (*naming, error) {
+	def := DefaultNaming
+	// Each template is tried out with only the variables that it is
+	// documented to be executed with, so that templates using the
+	// others (which would be empty) are rejected.
+	iface := map[string]string{"Interface": "Foo"}
+	typ := map[string]string{"Interface": "Foo", "Type": "mockFoo"}
+	method := map[string]string{"Method": "Bar"}
+	fields := []struct {
+		name     string
+		text     *string
+		fallback string
+		sample   map[string]string
+	}{
+		{"type", &n.Type, def.Type, iface},
+		{"constructor", &n.Constructor, def.Constructor, typ},
+		{"called", &n.Called, def.Called, method},
+		{"input", &n.Input, def.Input, method},
+		{"output", &n.Output, def.Output, method},
+		{"side effect", &n.SideEffect, def.SideEffect, method},
+		{"empty output", &n.EmptyOutput, def.EmptyOutput, method},
+		{"func", &n.Func, def.Func, method},
+		{"calls", &n.Calls, def.Calls, method},
+		{"spy", &n.Spy, def.Spy, iface},
+		{"results", &n.Results, def.Results, method},
+		{"returns", &n.Returns, def.Returns, method},
+		{"always returns", &n.AlwaysReturns, def.AlwaysReturns, method},
+		{"args", &n.Args, def.Args, method},
+		{"call", &n.Call, def.Call, method},
+		{"receiver", &n.Receiver, def.Receiver, iface},
+	}
+	parsed := make([]*template.Template, 0, len(fields))
+	for _, f := range fields {
+		if *f.text == "" {
+			*f.text = f.fallback
+		}
+		t, err := template.New(f.name).Funcs(NameFuncs).Option("missingkey=error").Parse(*f.text)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s name: %s", f.name, err)
+		}
+		sample, err := execName(t, f.sample)
+		if err != nil {
+			return nil, fmt.Errorf("could not execute %s name: %s", f.name, err)
+		}
+		if !token.IsIdentifier(sample) {
+			return nil, fmt.Errorf("%s name %q is not a valid identifier (from %q)", f.name, sample, *f.text)
+		}
+		parsed = append(parsed, t)
+	}
+	return &naming{
+		typ:           parsed[0],
+		constructor:   parsed[1],
+		called:        parsed[2],
+		input:         parsed[3],
+		output:        parsed[4],
+		sideEffect:    parsed[5],
+		emptyOutput:   parsed[6],
+		fn:            parsed[7],
+		calls:         parsed[8],
+		spy:           parsed[9],
+		results:       parsed[10],
+		returns:       parsed[11],
+		alwaysReturns: parsed[12],
+		args:          parsed[13],
+		call:          parsed[14],
+		receiver:      parsed[15],
+		tagged: n.Called != def.Called || n.Input != def.Input || n.Output != def.Output ||
+			n.SideEffect != def.SideEffect,
+		text: n,
+	}, nil
+}
+
+func execName(t *template.Template, data interface{}) (string, error) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// name executes t with data.  Since every template was executed
+// successfully when it was parsed, and the data always has the same
+// fields, errors are not expected here.
+func (n *naming) name(t *template.Template, data nameData) string {
+	name, err := execName(t, data)
+	if err != nil {
+		panic(fmt.Errorf("hel: could not execute %s name: %s", t.Name(), err))
+	}
+	return name
+}
+
+// snake converts a camel case name to snake case, e.g. HTTPClient
+// becomes http_client.
+func snake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// title upper-cases the first letter of name.
+func title(name string) string {
+	if name == "" {
+		return name
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
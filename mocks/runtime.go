@@ -0,0 +1,193 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+)
+
+const (
+	// runtimePath is the import path of the package that runtime style
+	// mocks are built on.
+	runtimePath = "github.com/nelsam/hel/helrt"
+
+	// runtimePkg is the name of the package at runtimePath.
+	runtimePkg = "helrt"
+)
+
+// runtimeStyle returns whether m is a runtime style mock.
+func (m Mock) runtimeStyle() bool {
+	return m.style() == StyleRuntime
+}
+
+// runtimeFields allocates the names of the fields and methods of m,
+// which is a runtime style mock, in s.
+func (m Mock) runtimeFields(s *scope, methods []Method) mockFields {
+	n := m.settings.naming
+	fields := mockFields{methods: make(map[string]methodFields, len(methods))}
+	for _, method := range methods {
+		data := nameData{Method: method.name}
+		f := methodFields{call: s.name(n.name(n.call, data))}
+		if m.settings.helpers {
+			if method.hasResults() {
+				f.returns = s.name(n.name(n.returns, data))
+				f.alwaysReturns = s.name(n.name(n.alwaysReturns, data))
+			}
+			if method.hasInputs() {
+				f.args = s.name(n.name(n.args, data))
+			}
+		}
+		fields.methods[method.name] = f
+	}
+	return fields
+}
+
+// resultType returns the type of the values that m's call queue
+// returns, which hold the results of each call.
+func (m Method) resultType(sig signature) *ast.StructType {
+	if !m.hasResults() {
+		return emptyStruct()
+	}
+	return m.recordStruct(sig.outputs)
+}
+
+// runtimeStructType returns the struct type of m, which is a runtime
+// style mock.
+func (m Mock) runtimeStructType() *ast.StructType {
+	structType := &ast.StructType{Fields: &ast.FieldList{}}
+	for _, method := range m.Methods() {
+		sig := method.signature()
+		structType.Fields.List = append(structType.Fields.List, &ast.Field{
+			Names: []*ast.Ident{{Name: method.fieldNames().call}},
+			Type: &ast.IndexListExpr{
+				X:       selectors(runtimePkg, "Call"),
+				Indices: []ast.Expr{method.callType(sig), method.resultType(sig)},
+			},
+		})
+	}
+	return structType
+}
+
+// runtimeAst returns all declaration AST for m, which is a runtime
+// style mock.
+func (m Mock) runtimeAst(chanSize int) []ast.Decl {
+	body := []ast.Stmt{&ast.AssignStmt{
+		Lhs: []ast.Expr{&ast.Ident{Name: "m"}},
+		Tok: token.DEFINE,
+		Rhs: []ast.Expr{&ast.UnaryExpr{Op: token.AND, X: &ast.CompositeLit{Type: &ast.Ident{Name: m.Name()}}}},
+	}}
+	for _, method := range m.Methods() {
+		body = append(body, &ast.ExprStmt{X: &ast.CallExpr{
+			Fun: selectors("m", method.fieldNames().call, "Init"),
+			Args: []ast.Expr{
+				&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(method.name)},
+				&ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(method.chanSize(ChanInput, chanSize))},
+				&ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(method.chanSize(ChanOutput, chanSize))},
+			},
+		}})
+	}
+	body = append(body, &ast.ReturnStmt{Results: []ast.Expr{&ast.Ident{Name: "m"}}})
+	decls := []ast.Decl{
+		m.Decl(),
+		&ast.FuncDecl{
+			Name: &ast.Ident{Name: m.ConstructorName()},
+			Type: &ast.FuncType{
+				Params:  &ast.FieldList{},
+				Results: &ast.FieldList{List: []*ast.Field{{Type: &ast.StarExpr{X: &ast.Ident{Name: m.Name()}}}}},
+			},
+			Body: &ast.BlockStmt{List: body},
+		},
+	}
+	for _, method := range m.Methods() {
+		decls = append(decls, method.runtimeAst())
+		decls = append(decls, method.helpers()...)
+	}
+	return decls
+}
+
+// callQueue returns an expression for m's call queue.
+func (m Method) callQueue() *ast.SelectorExpr {
+	return selectors(m.receiver.receiverName(), m.fieldNames().call)
+}
+
+// queueCall returns a call to the method called name on m's call
+// queue.
+func (m Method) queueCall(name string, args ...ast.Expr) *ast.CallExpr {
+	return &ast.CallExpr{Fun: &ast.SelectorExpr{X: m.callQueue(), Sel: &ast.Ident{Name: name}}, Args: args}
+}
+
+// runtimeAst returns the ast representation of m in a runtime style
+// mock, which sends its arguments to its call queue and, if it has
+// results, returns the results that the queue returns.
+func (m Method) runtimeAst() *ast.FuncDecl {
+	sig := m.signature()
+	in := &ast.CompositeLit{Type: m.callType(sig)}
+	for _, param := range sig.params {
+		for _, n := range param.Names {
+			in.Elts = append(in.Elts, &ast.Ident{Name: n.Name})
+		}
+	}
+	var stmts []ast.Stmt
+	if m.hasResults() {
+		ret := &ast.ReturnStmt{}
+		for _, out := range sig.outputs {
+			for _, n := range out.Names {
+				ret.Results = append(ret.Results, selectors(sig.record, n.Name))
+			}
+		}
+		stmts = []ast.Stmt{
+			&ast.AssignStmt{
+				Lhs: []ast.Expr{&ast.Ident{Name: sig.record}},
+				Tok: token.DEFINE,
+				Rhs: []ast.Expr{m.queueCall("Invoke", in)},
+			},
+			ret,
+		}
+	} else {
+		stmts = []ast.Stmt{&ast.ExprStmt{X: m.queueCall("Record", in)}}
+	}
+	return &ast.FuncDecl{
+		Doc:  commentGroup(m.receiver.copiedDoc(m.doc)),
+		Name: &ast.Ident{Name: m.name},
+		Type: m.mockType(),
+		Recv: m.recv(),
+		Body: &ast.BlockStmt{List: stmts},
+	}
+}
+
+// runtimeReturns returns the body of m's Returns helper in a runtime
+// style mock.
+func (m Method) runtimeReturns(sig signature, values []ast.Expr) []ast.Stmt {
+	out := &ast.CompositeLit{Type: m.resultType(sig), Elts: values}
+	return []ast.Stmt{&ast.ExprStmt{X: m.queueCall("Return", out)}}
+}
+
+// runtimeAlwaysReturns returns the body of m's AlwaysReturns helper in
+// a runtime style mock.
+func (m Method) runtimeAlwaysReturns(sig signature, values []ast.Expr) []ast.Stmt {
+	out := &ast.CompositeLit{Type: m.resultType(sig), Elts: values}
+	return []ast.Stmt{&ast.ReturnStmt{Results: []ast.Expr{m.queueCall("AlwaysReturn", out)}}}
+}
+
+// runtimeArgs returns the body of m's Args helper in a runtime style
+// mock, which returns the fields of the arguments from m's call queue.
+func (m Method) runtimeArgs(sig signature, in string) []ast.Stmt {
+	ret := &ast.ReturnStmt{}
+	for _, input := range sig.inputs {
+		for _, n := range input.Names {
+			ret.Results = append(ret.Results, selectors(in, n.Name))
+		}
+	}
+	return []ast.Stmt{
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{&ast.Ident{Name: in}},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{m.queueCall("Args")},
+		},
+		ret,
+	}
+}
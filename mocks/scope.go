@@ -0,0 +1,93 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// scope allocates identifiers within a single scope of generated code
+// (e.g. the file, a struct's fields, or a method's parameters),
+// ensuring that each one is unique and valid.
+type scope struct {
+	taken map[string]bool
+}
+
+func newScope(reserved ...string) *scope {
+	s := &scope{taken: make(map[string]bool)}
+	s.reserve(reserved...)
+	return s
+}
+
+// bodyPackages are the packages that generated function bodies may
+// refer to.
+var bodyPackages = []string{"fmt", "reflect", "sync", "testing", "time"}
+
+// predeclared are the identifiers in Go's universe scope.
+var predeclared = []string{
+	"any", "bool", "byte", "comparable", "complex64", "complex128", "error", "float32", "float64",
+	"int", "int8", "int16", "int32", "int64", "rune", "string", "uint", "uint8", "uint16", "uint32",
+	"uint64", "uintptr", "true", "false", "iota", "nil", "append", "cap", "clear", "close", "complex",
+	"copy", "delete", "imag", "len", "make", "max", "min", "new", "panic", "print", "println", "real",
+	"recover",
+}
+
+// newVarScope returns a scope for the variables (including parameters
+// and results) of a generated function.  The predeclared identifiers and
+// the packages that generated bodies refer to are reserved, so that
+// variables never shadow them.
+func newVarScope(reserved ...string) *scope {
+	s := newScope(reserved...)
+	s.reserve(predeclared...)
+	s.reserve(bodyPackages...)
+	return s
+}
+
+// reserve marks names as used without allocating them.
+func (s *scope) reserve(names ...string) {
+	for _, name := range names {
+		s.taken[name] = true
+	}
+}
+
+// name allocates an identifier based on base.  If base is already used
+// in s (or is a keyword), underscores are appended until it is unique.
+func (s *scope) name(base string) string {
+	name := base
+	for s.taken[name] || token.IsKeyword(name) {
+		name += "_"
+	}
+	s.taken[name] = true
+	return name
+}
+
+// nameOr allocates base if it is not used in s, or otherwise an
+// identifier based on alt.
+func (s *scope) nameOr(base, alt string) string {
+	if !s.taken[base] {
+		return s.name(base)
+	}
+	return s.name(alt)
+}
+
+// packageNames returns the names of the packages that are referenced
+// by qualified identifiers (e.g. the io in io.Reader) in exprs.
+func packageNames(exprs ...ast.Expr) []string {
+	var names []string
+	for _, expr := range exprs {
+		ast.Inspect(expr, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if pkg, ok := sel.X.(*ast.Ident); ok {
+				names = append(names, pkg.Name)
+			}
+			return false
+		})
+	}
+	return names
+}
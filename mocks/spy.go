@@ -0,0 +1,104 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// spyName returns the name of m's spy type.
+func (m Mock) spyName() string {
+	if m.settings.spyName != "" {
+		return m.settings.spyName
+	}
+	n := m.settings.naming
+	return n.name(n.spy, nameData{Interface: m.name})
+}
+
+// spyConstructorName returns the name of the constructor of m's spy.
+func (m Mock) spyConstructorName() string {
+	if m.settings.spyConstructorName != "" {
+		return m.settings.spyConstructorName
+	}
+	n := m.settings.naming
+	return n.name(n.constructor, nameData{Interface: m.name, Type: m.spyName()})
+}
+
+// spy returns the spy for m's interface.  Spies have their own
+// settings, which leave out the options that only apply to mocks.
+func (m Mock) spy() Mock {
+	s := *m.settings
+	s.spy = true
+	s.blockingReturn = false
+	s.sideEffects = false
+	s.verify = false
+	s.lifecycle = false
+	s.context = false
+	s.emptyOutput = ""
+	s.style = StyleChan
+	s.mockName, s.constructorName = m.spyName(), m.spyConstructorName()
+	m.settings = &s
+	return m
+}
+
+// interfaceType returns the expression for the interface type that m
+// mocks.
+func (m Mock) interfaceType() ast.Expr {
+	if m.settings.pkg != "" {
+		return selectors(m.settings.pkg, m.typeName)
+	}
+	return &ast.Ident{Name: m.typeName}
+}
+
+// implField returns the field which holds the implementation that m,
+// which is a spy, forwards its calls to.
+func (m Mock) implField() *ast.Field {
+	return &ast.Field{
+		Names: []*ast.Ident{{Name: m.fields().impl}},
+		Type:  m.interfaceType(),
+	}
+}
+
+// spyReturns returns the statements which forward a call to m's
+// implementation, send the results on m's results channels, and
+// return them.
+func (m Method) spyReturns() []ast.Stmt {
+	sig := m.signature()
+	recv := m.receiver.receiverName()
+	call := &ast.CallExpr{Fun: selectors(recv, m.receiver.fields().impl, m.name)}
+	for _, param := range sig.params {
+		for _, n := range param.Names {
+			call.Args = append(call.Args, &ast.Ident{Name: n.Name})
+		}
+		if _, ok := param.Type.(*ast.Ellipsis); ok {
+			call.Ellipsis = 1
+		}
+	}
+	if len(sig.zeros) == 0 {
+		return []ast.Stmt{&ast.ExprStmt{X: call}}
+	}
+	results := make([]ast.Expr, 0, len(sig.zeros))
+	for _, name := range sig.zeros {
+		results = append(results, &ast.Ident{Name: name})
+	}
+	stmts := []ast.Stmt{&ast.AssignStmt{Lhs: results, Tok: token.DEFINE, Rhs: []ast.Expr{call}}}
+	resultsName := m.fieldNames().output
+	if m.receiver.settings.records {
+		stmts = append(stmts, m.send(m.sendOn(recv, resultsName), &ast.CompositeLit{
+			Type: m.recordStruct(sig.outputs),
+			Elts: results,
+		}))
+	} else {
+		i := 0
+		for _, out := range sig.outputs {
+			for _, n := range out.Names {
+				stmts = append(stmts, m.send(m.sendOn(recv, resultsName, n.Name), results[i]))
+				i++
+			}
+		}
+	}
+	return append(stmts, &ast.ReturnStmt{Results: results})
+}
@@ -0,0 +1,250 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+)
+
+// The styles of mocks that may be generated.
+const (
+	// StyleChan mocks send their arguments on channels and receive
+	// their return values from channels.
+	StyleChan = "chan"
+
+	// StyleFunc mocks record their arguments in a call history and
+	// delegate to func fields.
+	StyleFunc = "func"
+
+	// StyleRuntime mocks are thin wrappers around the call queues in
+	// the helrt package.
+	StyleRuntime = "runtime"
+)
+
+// styleDirective is the name of the directive that sets the style of
+// an interface's mock.
+const styleDirective = "style"
+
+// checkStyle returns an error if style is not a valid style.
+func checkStyle(style string) error {
+	switch style {
+	case StyleChan, StyleFunc, StyleRuntime:
+		return nil
+	}
+	return fmt.Errorf("invalid style %q: must be %s, %s, or %s", style, StyleChan, StyleFunc, StyleRuntime)
+}
+
+// style returns m's style, which is set (in order of precedence) by
+// its interface's directive or its settings.  Spies always use
+// channels.
+func (m Mock) style() string {
+	if m.settings.spy {
+		return StyleChan
+	}
+	if style, ok := directive(m.interfaceDoc, styleDirective); ok {
+		return style
+	}
+	if m.settings.style == "" {
+		return StyleChan
+	}
+	return m.settings.style
+}
+
+// funcStyle returns whether m is a func style mock.
+func (m Mock) funcStyle() bool {
+	return m.style() == StyleFunc
+}
+
+// funcFields allocates the names of the fields and methods of m, which
+// is a func style mock, in s.
+func (m Mock) funcFields(s *scope, methods []Method) mockFields {
+	n := m.settings.naming
+	fields := mockFields{methods: make(map[string]methodFields, len(methods))}
+	// The history field embeds a sync.Mutex, so its fields must not
+	// hide the mutex or its methods.
+	history := newScope("Mutex", "Lock", "Unlock", "TryLock")
+	for _, method := range methods {
+		data := nameData{Method: method.name}
+		fields.methods[method.name] = methodFields{
+			fn:      s.name(n.name(n.fn, data)),
+			calls:   s.name(n.name(n.calls, data)),
+			history: history.name(method.name),
+		}
+	}
+	fields.history = s.name("history")
+	return fields
+}
+
+// funcStructType returns the struct type of m, which is a func style
+// mock.
+func (m Mock) funcStructType() *ast.StructType {
+	fields := m.fields()
+	history := &ast.StructType{Fields: &ast.FieldList{List: []*ast.Field{{Type: selectors("sync", "Mutex")}}}}
+	structType := &ast.StructType{Fields: &ast.FieldList{}}
+	for _, method := range m.Methods() {
+		names := method.fieldNames()
+		structType.Fields.List = append(structType.Fields.List, &ast.Field{
+			Names: []*ast.Ident{{Name: names.fn}},
+			Type:  method.mockType(),
+		})
+		history.Fields.List = append(history.Fields.List, &ast.Field{
+			Names: []*ast.Ident{{Name: names.history}},
+			Type:  &ast.ArrayType{Elt: method.callType(method.signature())},
+		})
+	}
+	structType.Fields.List = append(structType.Fields.List, &ast.Field{
+		Names: []*ast.Ident{{Name: fields.history}},
+		Type:  history,
+	})
+	return structType
+}
+
+// funcAst returns all declaration AST for m, which is a func style
+// mock.
+func (m Mock) funcAst() []ast.Decl {
+	decls := []ast.Decl{
+		m.Decl(),
+		&ast.FuncDecl{
+			Name: &ast.Ident{Name: m.ConstructorName()},
+			Type: &ast.FuncType{
+				Params:  &ast.FieldList{},
+				Results: &ast.FieldList{List: []*ast.Field{{Type: &ast.StarExpr{X: &ast.Ident{Name: m.Name()}}}}},
+			},
+			Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.AssignStmt{
+					Lhs: []ast.Expr{&ast.Ident{Name: "m"}},
+					Tok: token.DEFINE,
+					Rhs: []ast.Expr{&ast.UnaryExpr{Op: token.AND, X: &ast.CompositeLit{Type: &ast.Ident{Name: m.Name()}}}},
+				},
+				&ast.ReturnStmt{Results: []ast.Expr{&ast.Ident{Name: "m"}}},
+			}},
+		},
+	}
+	for _, method := range m.Methods() {
+		decls = append(decls, method.funcAst(), method.callsAst())
+	}
+	return decls
+}
+
+// callType returns the type of the values in m's call history, which
+// hold the arguments of each call.
+func (m Method) callType(sig signature) *ast.StructType {
+	typ := m.recordStruct(sig.inputs)
+	if len(typ.Fields.List) == 0 {
+		return emptyStruct()
+	}
+	return typ
+}
+
+// historyField returns an expression for m's call history.
+func (m Method) historyField() ast.Expr {
+	return selectors(m.receiver.receiverName(), m.receiver.fields().history, m.fieldNames().history)
+}
+
+// funcAst returns the ast representation of m in a func style mock.
+func (m Method) funcAst() *ast.FuncDecl {
+	sig := m.signature()
+	recv := m.receiver.receiverName()
+	history := selectors(recv, m.receiver.fields().history)
+	fn := selectors(recv, m.fieldNames().fn)
+
+	record := &ast.CompositeLit{Type: m.callType(sig)}
+	call := &ast.CallExpr{Fun: fn}
+	var args []ast.Expr
+	for _, param := range sig.params {
+		for _, n := range param.Names {
+			record.Elts = append(record.Elts, &ast.Ident{Name: n.Name})
+			args = append(args, &ast.Ident{Name: n.Name})
+		}
+		if _, ok := param.Type.(*ast.Ellipsis); ok {
+			call.Ellipsis = 1
+		}
+	}
+	call.Args = args
+
+	var delegate ast.Stmt = &ast.ExprStmt{X: call}
+	if m.implements.Results != nil {
+		delegate = &ast.ReturnStmt{Results: []ast.Expr{call}}
+	}
+	stmts := []ast.Stmt{
+		&ast.ExprStmt{X: &ast.CallExpr{Fun: &ast.SelectorExpr{X: history, Sel: &ast.Ident{Name: "Lock"}}}},
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{m.historyField()},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{&ast.CallExpr{Fun: &ast.Ident{Name: "append"}, Args: []ast.Expr{m.historyField(), record}}},
+		},
+		&ast.ExprStmt{X: &ast.CallExpr{Fun: &ast.SelectorExpr{X: history, Sel: &ast.Ident{Name: "Unlock"}}}},
+		&ast.IfStmt{
+			Cond: &ast.BinaryExpr{X: fn, Op: token.EQL, Y: &ast.Ident{Name: "nil"}},
+			Body: &ast.BlockStmt{List: m.funcFallback(sig, args)},
+		},
+		delegate,
+	}
+	return &ast.FuncDecl{
+		Doc:  commentGroup(m.receiver.copiedDoc(m.doc)),
+		Name: &ast.Ident{Name: m.name},
+		Type: m.mockType(),
+		Recv: m.recv(),
+		Body: &ast.BlockStmt{List: stmts},
+	}
+}
+
+// funcFallback returns the statements that m runs in a func style mock
+// when its func field is not set: it returns zero values if its empty
+// output policy is EmptyOutputZero, and panics otherwise.
+func (m Method) funcFallback(sig signature, args []ast.Expr) []ast.Stmt {
+	if m.emptyOutput() != EmptyOutputZero {
+		msg := fmt.Sprintf("%s.%s was called with %%v, but %s is not set", m.receiver.Name(), m.name, m.fieldNames().fn)
+		return []ast.Stmt{&ast.ExprStmt{X: &ast.CallExpr{
+			Fun: &ast.Ident{Name: "panic"},
+			Args: []ast.Expr{&ast.CallExpr{
+				Fun: selectors("fmt", "Sprintf"),
+				Args: []ast.Expr{
+					&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(msg)},
+					&ast.CompositeLit{
+						Type: &ast.ArrayType{Elt: &ast.InterfaceType{Methods: &ast.FieldList{Opening: 1, Closing: 1}}},
+						Elts: args,
+					},
+				},
+			}},
+		}}}
+	}
+	if m.implements.Results == nil {
+		return []ast.Stmt{&ast.ReturnStmt{}}
+	}
+	zeros, ret := m.zeroReturn(sig)
+	return []ast.Stmt{zeros, ret}
+}
+
+// callsAst returns the method which returns m's call history in a func
+// style mock.
+func (m Method) callsAst() *ast.FuncDecl {
+	recv := m.receiver.receiverName()
+	history := selectors(recv, m.receiver.fields().history)
+	typ := &ast.ArrayType{Elt: m.callType(m.signature())}
+	names := m.fieldNames()
+	return &ast.FuncDecl{
+		Doc: commentGroup(wrap(fmt.Sprintf("%s returns the arguments of each call to %s, in order.", names.calls, m.name),
+			docWidth)),
+		Name: &ast.Ident{Name: names.calls},
+		Recv: m.recv(),
+		Type: &ast.FuncType{Params: &ast.FieldList{}, Results: &ast.FieldList{List: []*ast.Field{{Type: typ}}}},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.ExprStmt{X: &ast.CallExpr{Fun: &ast.SelectorExpr{X: history, Sel: &ast.Ident{Name: "Lock"}}}},
+			&ast.DeferStmt{Call: &ast.CallExpr{Fun: &ast.SelectorExpr{X: history, Sel: &ast.Ident{Name: "Unlock"}}}},
+			&ast.ReturnStmt{Results: []ast.Expr{&ast.CallExpr{
+				Fun: &ast.Ident{Name: "append"},
+				Args: []ast.Expr{
+					&ast.CallExpr{Fun: typ, Args: []ast.Expr{&ast.Ident{Name: "nil"}}},
+					m.historyField(),
+				},
+				Ellipsis: 1,
+			}}},
+		}},
+	}
+}
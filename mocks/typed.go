@@ -0,0 +1,229 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+)
+
+// helpers returns the typed helper methods for m, or nil if m's mock
+// does not have them.
+func (m Method) helpers() []ast.Decl {
+	names := m.fieldNames()
+	var decls []ast.Decl
+	if names.returns != "" {
+		decls = append(decls, m.returnsHelper(), m.alwaysReturnsHelper())
+	}
+	if names.args != "" {
+		decls = append(decls, m.argsHelper())
+	}
+	return decls
+}
+
+// helperDecl returns a method of m's mock called name, with the
+// passed in doc comment, parameters, results, and body.
+func (m Method) helperDecl(name, doc string, params, results []*ast.Field, body []ast.Stmt) *ast.FuncDecl {
+	return &ast.FuncDecl{
+		Doc:  commentGroup(wrap(doc, docWidth)),
+		Recv: m.recv(),
+		Name: &ast.Ident{Name: name},
+		Type: &ast.FuncType{
+			Params:  &ast.FieldList{List: params},
+			Results: &ast.FieldList{List: results},
+		},
+		Body: &ast.BlockStmt{List: body},
+	}
+}
+
+// resultParams returns the parameters of m's Returns helpers, which
+// match m's results.  Unnamed (and blank) results are named like the
+// zero values in sig.  The names of all of the parameters are also
+// returned, in order.
+func (m Method) resultParams(sig signature) ([]*ast.Field, []ast.Expr) {
+	var (
+		params []*ast.Field
+		names  []ast.Expr
+	)
+	for _, f := range sig.results {
+		param := &ast.Field{Type: f.Type}
+		idents := f.Names
+		if idents == nil {
+			idents = []*ast.Ident{{Name: "_"}}
+		}
+		for _, n := range idents {
+			name := n.Name
+			if name == "_" {
+				name = sig.zeros[len(names)]
+			}
+			param.Names = append(param.Names, &ast.Ident{Name: name})
+			names = append(names, &ast.Ident{Name: name})
+		}
+		params = append(params, param)
+	}
+	return params, names
+}
+
+// outputSends returns the send statements which queue values on m's
+// output channels.  values must be in the same order as m's results.
+func (m Method) outputSends(sig signature, values []ast.Expr) []*ast.SendStmt {
+	recv := m.receiver.receiverName()
+	output := m.fieldNames().output
+	if m.receiver.settings.records {
+		send := m.sendOn(recv, output)
+		send.Value = &ast.CompositeLit{Type: m.recordStruct(sig.outputs), Elts: values}
+		return []*ast.SendStmt{send}
+	}
+	var sends []*ast.SendStmt
+	for _, out := range sig.outputs {
+		for _, n := range out.Names {
+			send := m.sendOn(recv, output, n.Name)
+			send.Value = values[len(sends)]
+			sends = append(sends, send)
+		}
+	}
+	return sends
+}
+
+// returnsHelper returns the helper which queues one set of return
+// values for m.
+func (m Method) returnsHelper() *ast.FuncDecl {
+	sig := m.signature()
+	params, values := m.resultParams(sig)
+	var body []ast.Stmt
+	if m.receiver.runtimeStyle() {
+		body = m.runtimeReturns(sig, values)
+	} else {
+		for _, send := range m.outputSends(sig, values) {
+			body = append(body, send)
+		}
+	}
+	name := m.fieldNames().returns
+	doc := fmt.Sprintf("%s queues one set of return values for a call to %s.", name, m.name)
+	return m.helperDecl(name, doc, params, nil, body)
+}
+
+// alwaysReturnsHelper returns the helper which keeps returning the
+// same values from calls to m until the func that it returns is called.
+// Unlike pers.ConsistentlyReturn, it does not fill m's output channels:
+// it hands one set of values to each call, as the call is made, so that
+// none are left over once it is stopped.
+func (m Method) alwaysReturnsHelper() *ast.FuncDecl {
+	sig := m.signature()
+	params, values := m.resultParams(sig)
+	vars := newVarScope(m.receiver.receiverName())
+	for _, v := range values {
+		vars.reserve(v.(*ast.Ident).Name)
+	}
+	results := []*ast.Field{{
+		Names: []*ast.Ident{{Name: vars.name("stop")}},
+		Type:  &ast.FuncType{Params: &ast.FieldList{}},
+	}}
+	name := m.fieldNames().alwaysReturns
+	doc := fmt.Sprintf("%s keeps returning the same values from calls to %s which have no other output "+
+		"queued, until stop is called.  Values are only handed to calls as they are made, so none are left "+
+		"over after stop is called.", name, m.name)
+	if m.receiver.runtimeStyle() {
+		return m.helperDecl(name, doc, params, results, m.runtimeAlwaysReturns(sig, values))
+	}
+	done, exited := vars.name("done"), vars.name("exited")
+
+	cases := []ast.Stmt{&ast.CommClause{Comm: &ast.SendStmt{
+		Chan:  selectors(m.receiver.receiverName(), m.fieldNames().always),
+		Value: &ast.CompositeLit{Type: m.recordStruct(sig.outputs), Elts: values},
+	}}}
+	cases = append(cases, &ast.CommClause{
+		Comm: &ast.ExprStmt{X: &ast.UnaryExpr{Op: token.ARROW, X: &ast.Ident{Name: done}}},
+		Body: []ast.Stmt{&ast.ReturnStmt{}},
+	})
+	closeCall := func(name string) *ast.CallExpr {
+		return &ast.CallExpr{Fun: &ast.Ident{Name: "close"}, Args: []ast.Expr{&ast.Ident{Name: name}}}
+	}
+	makeChan := func() ast.Expr {
+		return &ast.CallExpr{
+			Fun:  &ast.Ident{Name: "make"},
+			Args: []ast.Expr{&ast.ChanType{Dir: ast.SEND | ast.RECV, Value: emptyStruct()}},
+		}
+	}
+	send := &ast.FuncLit{
+		Type: &ast.FuncType{Params: &ast.FieldList{}},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.DeferStmt{Call: closeCall(exited)},
+			&ast.ForStmt{Body: &ast.BlockStmt{List: []ast.Stmt{
+				&ast.SelectStmt{Body: &ast.BlockStmt{List: cases}},
+			}}},
+		}},
+	}
+	stop := &ast.FuncLit{
+		Type: &ast.FuncType{Params: &ast.FieldList{}},
+		Body: &ast.BlockStmt{List: []ast.Stmt{
+			&ast.ExprStmt{X: closeCall(done)},
+			&ast.ExprStmt{X: &ast.UnaryExpr{Op: token.ARROW, X: &ast.Ident{Name: exited}}},
+		}},
+	}
+	body := []ast.Stmt{
+		&ast.AssignStmt{
+			Lhs: []ast.Expr{&ast.Ident{Name: done}, &ast.Ident{Name: exited}},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{makeChan(), makeChan()},
+		},
+		&ast.GoStmt{Call: &ast.CallExpr{Fun: send}},
+		&ast.ReturnStmt{Results: []ast.Expr{stop}},
+	}
+	return m.helperDecl(name, doc, params, results, body)
+}
+
+// argsHelper returns the helper which waits for a call to m and
+// returns its arguments.
+func (m Method) argsHelper() *ast.FuncDecl {
+	sig := m.signature()
+	recv := m.receiver.receiverName()
+	names := m.fieldNames()
+
+	var results []*ast.Field
+	for _, param := range sig.params {
+		typ := param.Type
+		if ellipsis, ok := typ.(*ast.Ellipsis); ok {
+			typ = &ast.ArrayType{Elt: ellipsis.Elt}
+		}
+		results = append(results, &ast.Field{Names: param.Names, Type: typ})
+	}
+	vars := newVarScope(recv)
+	for _, param := range sig.params {
+		for _, n := range param.Names {
+			vars.reserve(n.Name)
+		}
+	}
+	in := vars.name("in")
+	doc := fmt.Sprintf("%s waits for a call to %s and returns its arguments.", names.args, m.name)
+	if m.receiver.runtimeStyle() {
+		return m.helperDecl(names.args, doc, nil, results, m.runtimeArgs(sig, in))
+	}
+	body := []ast.Stmt{
+		&ast.ExprStmt{X: m.recvFrom(recv, names.called)},
+	}
+	ret := &ast.ReturnStmt{}
+	if m.receiver.settings.records {
+		body = append(body, &ast.AssignStmt{
+			Lhs: []ast.Expr{&ast.Ident{Name: in}},
+			Tok: token.DEFINE,
+			Rhs: []ast.Expr{m.recvFrom(recv, names.input)},
+		})
+		for _, input := range sig.inputs {
+			for _, n := range input.Names {
+				ret.Results = append(ret.Results, selectors(in, n.Name))
+			}
+		}
+	} else {
+		for _, input := range sig.inputs {
+			for _, n := range input.Names {
+				ret.Results = append(ret.Results, m.recvFrom(recv, names.input, n.Name))
+			}
+		}
+	}
+	body = append(body, ret)
+	return m.helperDecl(names.args, doc, nil, results, body)
+}
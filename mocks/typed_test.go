@@ -0,0 +1,60 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks_test
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/a8m/expect"
+	"github.com/nelsam/hel/mocks"
+)
+
+// alwaysReturnsFoo is the interface that alwaysReturnsTest's mock is
+// generated from.
+const alwaysReturnsFoo = `type Foo interface {
+	Foo(x int) string
+}`
+
+// alwaysReturnsTest is run against a mock of Foo which was generated
+// with typed helpers and verification.  It fails if stopping
+// FooAlwaysReturns leaves values behind, or if verify reports them.
+const alwaysReturnsTest = `package foo
+
+import "testing"
+
+func TestAlwaysReturns(t *testing.T) {
+	m := newMockFoo(t)
+
+	stop := m.FooAlwaysReturns("a")
+	for i := 0; i < 3; i++ {
+		if v := m.Foo(i); v != "a" {
+			t.Fatalf("expected a; got %v", v)
+		}
+		m.FooArgs()
+	}
+	stop()
+
+	m.FooReturns("b")
+	if v := m.Foo(3); v != "b" {
+		t.Fatalf("expected the value queued after stop; got %v", v)
+	}
+	m.FooArgs()
+}
+`
+
+func TestAlwaysReturns_Verify(t *testing.T) {
+	expect := expect.New(t)
+
+	mockFinder := newMockTypeFinder()
+	close(mockFinder.DependenciesOutput.Dependencies)
+	mockFinder.ExportedTypesOutput.Types <- []*ast.TypeSpec{typeSpec(expect, alwaysReturnsFoo)}
+	m, err := mocks.Generate(mockFinder)
+	expect(err).To.Be.Nil().Else.FailNow()
+	m.SetHelpers(true)
+	m.SetVerify(true)
+
+	runGenerated(t, expect, m, alwaysReturnsFoo, alwaysReturnsTest)
+}
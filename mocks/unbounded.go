@@ -0,0 +1,118 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// unboundedSrc is the source of the helper methods that unbounded
+// mocks use to send on their channels without blocking.  It is
+// formatted with the receiver name, the mock type name, the names of
+// the helpers and the backlog field, and then the names of their
+// variables and the statement which sends a value.
+const unboundedSrc = `func (%[1]s *%[2]s) %[3]s(%[6]s, %[7]s interface{}) {
+	%[8]s := reflect.ValueOf(%[6]s)
+	%[9]s := reflect.ValueOf(%[7]s)
+	if %[7]s == nil {
+		%[9]s = reflect.Zero(%[8]s.Type().Elem())
+	}
+	%[1]s.%[5]s.Lock()
+	defer %[1]s.%[5]s.Unlock()
+	%[10]s, %[11]s := %[1]s.%[5]s.values[%[6]s]
+	if !%[11]s && %[8]s.TrySend(%[9]s) {
+		return
+	}
+	if %[1]s.%[5]s.values == nil {
+		%[1]s.%[5]s.values = make(map[interface{}][]reflect.Value)
+	}
+	%[1]s.%[5]s.values[%[6]s] = append(%[10]s, %[9]s)
+	if !%[11]s {
+		go %[1]s.%[4]s(%[8]s)
+	}
+}
+
+func (%[1]s *%[2]s) %[4]s(%[8]s reflect.Value) {
+	%[6]s := %[8]s.Interface()
+	%[1]s.%[5]s.Lock()
+	defer %[1]s.%[5]s.Unlock()
+	for len(%[1]s.%[5]s.values[%[6]s]) > 0 {
+		%[9]s := %[1]s.%[5]s.values[%[6]s][0]
+		%[1]s.%[5]s.Unlock()
+		%[12]s
+		%[1]s.%[5]s.Lock()
+		%[1]s.%[5]s.values[%[6]s] = %[1]s.%[5]s.values[%[6]s][1:]
+	}
+	delete(%[1]s.%[5]s.values, %[6]s)
+}
+`
+
+// closableSendSrc is the source of the statement which sends a value
+// from the backlog of a mock with lifecycle methods, which gives up
+// (dropping the backlog for the channel) if the mock is closed.  It is
+// formatted like unboundedSrc, followed by the names of the closed
+// field and the variable holding the chosen case.
+const closableSendSrc = `if %[14]s, _, _ := reflect.Select([]reflect.SelectCase{
+	{Dir: reflect.SelectSend, Chan: %[8]s, Send: %[9]s},
+	{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(%[1]s.%[13]s)},
+}); %[14]s == 1 {
+	%[1]s.%[5]s.Lock()
+	delete(%[1]s.%[5]s.values, %[6]s)
+	return
+}`
+
+// backlogField returns the field which holds the values that an
+// unbounded mock's channels had no room for, keyed by channel.
+func (m Mock) backlogField() *ast.Field {
+	return &ast.Field{
+		Names: []*ast.Ident{{Name: m.fields().backlog}},
+		Type: &ast.StructType{Fields: &ast.FieldList{List: []*ast.Field{
+			{Type: selectors("sync", "Mutex")},
+			{
+				Names: []*ast.Ident{{Name: "values"}},
+				Type: &ast.MapType{
+					Key:   &ast.InterfaceType{Methods: &ast.FieldList{Opening: 1, Closing: 1}},
+					Value: &ast.ArrayType{Elt: selectors("reflect", "Value")},
+				},
+			},
+		}}},
+	}
+}
+
+// unboundedHelpers returns the helper methods that m's methods use to
+// send on its channels without blocking, or nil if m is not unbounded.
+func (m Mock) unboundedHelpers() []ast.Decl {
+	if !m.settings.unbounded {
+		return nil
+	}
+	fields := m.fields()
+	recv := m.receiverName()
+	vars := newVarScope(recv)
+	args := []interface{}{recv, m.Name(), fields.enqueue, fields.flush, fields.backlog,
+		vars.name("ch"), vars.name("v"), vars.name("c"), vars.name("value"), vars.name("pending"), vars.name("flushing")}
+	send := fmt.Sprintf("%[8]s.Send(%[9]s)", args...)
+	if m.settings.lifecycle {
+		send = fmt.Sprintf(closableSendSrc, append(args, "", fields.closed, vars.name("chosen"))...)
+	}
+	src := fmt.Sprintf(unboundedSrc, append(args, send)...)
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package mocks\n\n"+src, 0)
+	if err != nil {
+		// The source is generated from a constant, so this would be a
+		// bug in hel.
+		panic(fmt.Errorf("hel: could not parse unbounded helpers: %s", err))
+	}
+	enqueue, flush := f.Decls[0].(*ast.FuncDecl), f.Decls[1].(*ast.FuncDecl)
+	clearPositions(enqueue)
+	clearPositions(flush)
+	enqueue.Doc = commentGroup(wrap(fmt.Sprintf("%s sends v on ch, which is one of %s's channels, without blocking.  "+
+		"If ch is full, or earlier values are still waiting to be sent on it, v is added to the backlog and sent "+
+		"by %s.", fields.enqueue, m.Name(), fields.flush), docWidth))
+	flush.Doc = commentGroup(wrap(fmt.Sprintf("%s sends the backlog of values for c, in order, until it is empty.",
+		fields.flush), docWidth))
+	return []ast.Decl{enqueue, flush}
+}
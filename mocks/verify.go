@@ -0,0 +1,174 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package mocks
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// verifyHelpersSrc is the source of the helper methods that verified
+// mocks use to track their calls and find values that were never
+// received.  It is formatted with the receiver name, the mock type
+// name, the names of the helpers and the blocked field, and then the
+// names of their variables.
+const verifyHelpersSrc = `func (%[1]s *%[2]s) %[3]s(%[6]s string, %[7]s ...interface{}) func() {
+	%[1]s.%[5]s.Lock()
+	defer %[1]s.%[5]s.Unlock()
+	if %[1]s.%[5]s.calls == nil {
+		%[1]s.%[5]s.calls = make(map[int]string)
+	}
+	%[8]s := %[1]s.%[5]s.next
+	%[1]s.%[5]s.next++
+	%[1]s.%[5]s.calls[%[8]s] = fmt.Sprintf("%%s was called with %%v", %[6]s, %[7]s)
+	return func() {
+		%[1]s.%[5]s.Lock()
+		defer %[1]s.%[5]s.Unlock()
+		delete(%[1]s.%[5]s.calls, %[8]s)
+	}
+}
+
+func (%[1]s *%[2]s) %[4]s(%[9]s ...interface{}) (%[10]s [][]interface{}) {
+	for {
+		var %[11]s []interface{}
+		for %[12]s, %[13]s := range %[9]s {
+			%[14]s, %[15]s := reflect.ValueOf(%[13]s).TryRecv()
+			if !%[15]s {
+				if %[12]s == 0 {
+					return %[10]s
+				}
+				continue
+			}
+			%[11]s = append(%[11]s, %[14]s.Interface())
+		}
+		%[10]s = append(%[10]s, %[11]s)
+	}
+}
+`
+
+// blockedField returns the field which holds the calls to a verified
+// mock that have not returned yet.
+func (m Mock) blockedField() *ast.Field {
+	return &ast.Field{
+		Names: []*ast.Ident{{Name: m.fields().blocked}},
+		Type: &ast.StructType{Fields: &ast.FieldList{List: []*ast.Field{
+			{Type: selectors("sync", "Mutex")},
+			{Names: []*ast.Ident{{Name: "next"}}, Type: &ast.Ident{Name: "int"}},
+			{
+				Names: []*ast.Ident{{Name: "calls"}},
+				Type:  &ast.MapType{Key: &ast.Ident{Name: "int"}, Value: &ast.Ident{Name: "string"}},
+			},
+		}}},
+	}
+}
+
+// track returns a statement which tracks a call to m until it
+// returns, so that calls which are still blocked when a test finishes
+// can be reported.
+func (m Method) track() ast.Stmt {
+	call := &ast.CallExpr{
+		Fun:  selectors(m.receiver.receiverName(), m.receiver.fields().track),
+		Args: []ast.Expr{&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(m.name)}},
+	}
+	for _, param := range m.signature().params {
+		for _, n := range param.Names {
+			call.Args = append(call.Args, &ast.Ident{Name: n.Name})
+		}
+	}
+	return &ast.DeferStmt{Call: &ast.CallExpr{Fun: call}}
+}
+
+// verifySrc returns the source of the method that reports values that
+// were sent on m's channels but never received, along with calls that
+// are still blocked.  It is registered with t.Cleanup by m's
+// constructor.
+func (m Mock) verifySrc() string {
+	fields := m.fields()
+	recv := m.receiverName()
+	vars := newVarScope(recv)
+	t, call, output := vars.name("t"), vars.name("call"), vars.name("output")
+	var b strings.Builder
+	fmt.Fprintf(&b, "func (%s *%s) %s(%s testing.TB) {\n", recv, m.Name(), fields.verify, t)
+	fmt.Fprintf(&b, "%s.Helper()\n", t)
+	for _, method := range m.Methods() {
+		sig := method.signature()
+		names := method.fieldNames()
+		chans := []string{recv + "." + names.called}
+		switch {
+		case len(sig.inputs) == 0:
+		case m.settings.records:
+			chans = append(chans, recv+"."+names.input)
+		default:
+			for _, in := range sig.inputs {
+				for _, n := range in.Names {
+					chans = append(chans, recv+"."+names.input+"."+n.Name)
+				}
+			}
+		}
+		fmt.Fprintf(&b, "for _, %s := range %s.%s(%s) {\n", call, recv, fields.unconsumed, strings.Join(chans, ", "))
+		fmt.Fprintf(&b, "%s.Errorf(%q, %s[1:])\n}\n", t,
+			fmt.Sprintf("%s.%s was called with %%v, but the call was not asserted on", m.Name(), method.name), call)
+
+		var outputs []string
+		switch {
+		case len(sig.outputs) == 0:
+		case m.settings.records:
+			outputs = append(outputs, recv+"."+names.output)
+		default:
+			for _, out := range sig.outputs {
+				for _, n := range out.Names {
+					outputs = append(outputs, recv+"."+names.output+"."+n.Name)
+				}
+			}
+		}
+		if len(outputs) > 0 {
+			fmt.Fprintf(&b, "for _, %s := range %s.%s(%s) {\n", output, recv, fields.unconsumed, strings.Join(outputs, ", "))
+			fmt.Fprintf(&b, "%s.Errorf(%q, %s)\n}\n", t,
+				fmt.Sprintf("%s.%s had output %%v queued, but it was not returned", m.Name(), method.name), output)
+		}
+	}
+	fmt.Fprintf(&b, "%s.%s.Lock()\n", recv, fields.blocked)
+	fmt.Fprintf(&b, "defer %s.%s.Unlock()\n", recv, fields.blocked)
+	fmt.Fprintf(&b, "for _, %s := range %s.%s.calls {\n", call, recv, fields.blocked)
+	fmt.Fprintf(&b, "%s.Errorf(%q, %s)\n}\n}\n", t, m.Name()+".%s, but the call was still blocked when the test finished", call)
+	return b.String()
+}
+
+// verifyHelpers returns the methods that a verified mock uses to
+// report the values that were never received from its channels, or
+// nil if m is not verified.
+func (m Mock) verifyHelpers() []ast.Decl {
+	if !m.settings.verify {
+		return nil
+	}
+	fields := m.fields()
+	recv := m.receiverName()
+	vars := newVarScope(recv)
+	src := m.verifySrc() + "\n" + fmt.Sprintf(verifyHelpersSrc, recv, m.Name(), fields.track, fields.unconsumed, fields.blocked,
+		vars.name("method"), vars.name("args"), vars.name("id"),
+		vars.name("chans"), vars.name("values"), vars.name("round"), vars.name("i"), vars.name("ch"), vars.name("v"), vars.name("ok"))
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package mocks\n\n"+src, 0)
+	if err != nil {
+		// The source is generated from constants and identifiers, so
+		// this would be a bug in hel.
+		panic(fmt.Errorf("hel: could not parse verify helpers: %s", err))
+	}
+	verify, track, unconsumed := f.Decls[0].(*ast.FuncDecl), f.Decls[1].(*ast.FuncDecl), f.Decls[2].(*ast.FuncDecl)
+	for _, decl := range []*ast.FuncDecl{verify, track, unconsumed} {
+		clearPositions(decl)
+	}
+	verify.Doc = commentGroup(wrap(fmt.Sprintf("%s reports calls to %s that were not asserted on, output that was "+
+		"queued but not returned, and calls that are still blocked.  %s registers it to run when the test finishes.",
+		fields.verify, m.Name(), m.ConstructorName()), docWidth))
+	track.Doc = commentGroup(wrap(fmt.Sprintf("%s records a call to %s, returning a func which removes it once the "+
+		"call returns.", fields.track, m.Name()), docWidth))
+	unconsumed.Doc = commentGroup(wrap(fmt.Sprintf("%s receives the values left in chans, which are sent on together, "+
+		"returning a slice of values for each time that the first of them was sent on.", fields.unconsumed), docWidth))
+	return []ast.Decl{verify, track, unconsumed}
+}
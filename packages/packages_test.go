@@ -47,7 +47,7 @@ func TestLoad(t *testing.T) {
 		expect(dirs[0].Path()).To(equal(filepath.Join(filepath.Dir(wd), "mocks")))
 
 		dirs = packages.Load("github.com/nelsam/hel/...")
-		expect(dirs).To(haveLen(7))
+		expect(dirs).To(haveLen(8))
 
 		dirs = packages.Load("github.com/nelsam/hel")
 		expect(dirs).To(haveLen(1))
@@ -14,12 +14,18 @@ import (
 // until the returned done function is called.  You may pass in either
 // a channel (in which case you should pass in a single argument) or a
 // struct full of channels (in which case you should pass in arguments
-// in the order the fields appear in the struct).
+// in the order the fields appear in the struct).  Channels of structs,
+// such as the ones that hel generates with --records, also accept the
+// struct's fields as arguments, in order, as do the call queues of
+// runtime style mocks (e.g. FooCall).
 //
 // After the returned function is called, you will still need to drain
 // any remaining calls from the channel(s) before it will start blocking
 // again.
 func ConsistentlyReturn(mock interface{}, args ...interface{}) (func(), error) {
+	if q, ok := queue(mock); ok {
+		return q.AlwaysReturnValues(args...)
+	}
 	cases, err := selectCases(mock, args...)
 	if err != nil {
 		return nil, err
@@ -37,6 +43,13 @@ func selectCases(mock interface{}, args ...interface{}) ([]reflect.SelectCase, e
 	v := reflect.ValueOf(mock)
 	switch v.Kind() {
 	case reflect.Chan:
+		if isRecord(v.Type().Elem(), args...) {
+			record, err := newRecord(v.Type().Elem(), args...)
+			if err != nil {
+				return nil, fmt.Errorf("%s for %#v", err, mock)
+			}
+			return []reflect.SelectCase{{Dir: reflect.SelectSend, Chan: v, Send: record}}, nil
+		}
 		if len(args) != 1 {
 			return nil, fmt.Errorf("expected 1 argument for %#v; got %d", mock, len(args))
 		}
@@ -71,6 +84,42 @@ func selectCases(mock interface{}, args ...interface{}) ([]reflect.SelectCase, e
 	}
 }
 
+// isRecord returns whether args are the fields of a value of type
+// elem, rather than a single value of type elem.
+func isRecord(elem reflect.Type, args ...interface{}) bool {
+	if elem.Kind() != reflect.Struct {
+		return false
+	}
+	if len(args) != 1 {
+		return true
+	}
+	return args[0] != nil && !reflect.TypeOf(args[0]).AssignableTo(elem)
+}
+
+// newRecord returns a value of the struct type typ with its fields
+// set to args.  Nil arguments leave their fields as the zero value.
+func newRecord(typ reflect.Type, args ...interface{}) (reflect.Value, error) {
+	if len(args) != typ.NumField() {
+		argString := "argument"
+		if typ.NumField() != 1 {
+			argString = "arguments"
+		}
+		return reflect.Value{}, fmt.Errorf("expected %d %s", typ.NumField(), argString)
+	}
+	record := reflect.New(typ).Elem()
+	for i, arg := range args {
+		if arg == nil {
+			continue
+		}
+		argV := reflect.ValueOf(arg)
+		if !argV.Type().AssignableTo(typ.Field(i).Type) {
+			return reflect.Value{}, fmt.Errorf("argument %d (%T) is not assignable to field %s (%s)", i, arg, typ.Field(i).Name, typ.Field(i).Type)
+		}
+		record.Field(i).Set(argV)
+	}
+	return record, nil
+}
+
 func consistentlyReturn(cases []reflect.SelectCase, done, exited chan struct{}, args ...interface{}) {
 	defer close(exited)
 	doneIdx := len(cases)
@@ -100,32 +100,18 @@ func (m HaveMethodExecutedMatcher) Match(v interface{}) (interface{}, error) {
 	if mv.Kind() == reflect.Ptr {
 		mv = mv.Elem()
 	}
-	calledField := mv.FieldByName(m.MethodName + "Called")
-	cases := []reflect.SelectCase{
-		{Dir: reflect.SelectRecv, Chan: calledField},
-	}
-	switch m.within {
-	case 0:
-		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
-	default:
-		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(time.After(m.within))})
+	inputs, called, err := m.receive(mv)
+	if err != nil {
+		return v, err
 	}
-
-	chosen, _, _ := reflect.Select(cases)
-	if chosen == 1 {
+	if !called {
 		return v, fmt.Errorf("pers: expected method %s to have been called, but it was not", m.MethodName)
 	}
-	inputField := mv.FieldByName(m.MethodName + "Input")
-	if !inputField.IsValid() {
+	if len(inputs) == 0 {
 		return v, nil
 	}
-
 	var calledWith []interface{}
-	for i := 0; i < inputField.NumField(); i++ {
-		fv, ok := inputField.Field(i).Recv()
-		if !ok {
-			return v, fmt.Errorf("pers: field %s is closed; cannot perform matches against this mock", inputField.Type().Field(i).Name)
-		}
+	for i, fv := range inputs {
 		calledWith = append(calledWith, fv.Interface())
 
 		if m.saveTo != nil {
@@ -157,6 +143,37 @@ func (m HaveMethodExecutedMatcher) Match(v interface{}) (interface{}, error) {
 	return v, fmt.Errorf(msg, m.MethodName, diff)
 }
 
+// receive waits for a call to m.MethodName on the mock struct mv and
+// receives its arguments.  called is false if no call was made in
+// time.
+func (m HaveMethodExecutedMatcher) receive(mv reflect.Value) (inputs []reflect.Value, called bool, err error) {
+	if q, ok := methodQueue(mv, m.MethodName); ok {
+		inputs, called = q.ReceiveArgs(m.within)
+		return inputs, called, nil
+	}
+	calledField := methodField(mv, m.MethodName, "called")
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: calledField},
+	}
+	switch m.within {
+	case 0:
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	default:
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(time.After(m.within))})
+	}
+
+	chosen, _, _ := reflect.Select(cases)
+	if chosen == 1 {
+		return nil, false, nil
+	}
+	inputField := methodField(mv, m.MethodName, "input")
+	if !inputField.IsValid() {
+		return nil, true, nil
+	}
+	inputs, err = receiveInputs(inputField)
+	return inputs, true, err
+}
+
 func (m HaveMethodExecutedMatcher) sliceDiff(actual, expected reflect.Value) (bool, string) {
 	if actual.Len() != expected.Len() {
 		return false, m.differ.Diff(fmt.Sprintf("length %d", actual.Len()), fmt.Sprintf("length %d", expected.Len()))
@@ -238,6 +255,48 @@ func (m HaveMethodExecutedMatcher) valueDiff(actual, expected reflect.Value) (bo
 	}
 }
 
+// receiveInputs receives the arguments of a single call from input,
+// which is either a struct of channels (one per argument) or, for
+// mocks generated with --records, a channel of record structs.
+func receiveInputs(input reflect.Value) ([]reflect.Value, error) {
+	if input.Kind() == reflect.Chan {
+		record, ok := input.Recv()
+		if !ok {
+			return nil, fmt.Errorf("pers: channel of type %s is closed; cannot perform matches against this mock", input.Type())
+		}
+		values := make([]reflect.Value, 0, record.NumField())
+		for i := 0; i < record.NumField(); i++ {
+			values = append(values, record.Field(i))
+		}
+		return values, nil
+	}
+	values := make([]reflect.Value, 0, input.NumField())
+	for i := 0; i < input.NumField(); i++ {
+		fv, ok := input.Field(i).Recv()
+		if !ok {
+			return nil, fmt.Errorf("pers: field %s is closed; cannot perform matches against this mock", input.Type().Field(i).Name)
+		}
+		values = append(values, fv)
+	}
+	return values, nil
+}
+
+// methodField returns the field of the mock struct v which holds the
+// channel(s) for role ("called", "input", or "output") of the method
+// named method.  Mocks generated with a custom naming scheme tag these
+// fields with `hel:"<method>,<role>"`; otherwise, the field is found
+// by hel's default name (e.g. FooCalled).
+func methodField(v reflect.Value, method, role string) reflect.Value {
+	t := v.Type()
+	tag := method + "," + role
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("hel") == tag {
+			return v.Field(i)
+		}
+	}
+	return v.FieldByName(method + strings.Title(role))
+}
+
 func isNil(v reflect.Value) bool {
 	switch v.Kind() {
 	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
@@ -90,6 +90,59 @@ func (m *fakeSliceMapMock) Foo(arg0 []interface{}, arg1 map[string]interface{})
 	m.FooInput.Arg1 <- arg1
 }
 
+type fakeTaggedMock struct {
+	FooWasCalled chan struct{} `hel:"Foo,called"`
+	FooArgs      struct {
+		Arg0 chan int
+	} `hel:"Foo,input"`
+}
+
+func newFakeTaggedMock() *fakeTaggedMock {
+	m := &fakeTaggedMock{}
+	m.FooWasCalled = make(chan struct{}, 100)
+	m.FooArgs.Arg0 = make(chan int, 100)
+	return m
+}
+
+func (m *fakeTaggedMock) Foo(arg0 int) {
+	m.FooWasCalled <- struct{}{}
+	m.FooArgs.Arg0 <- arg0
+}
+
+type fakeRecordMock struct {
+	FooCalled chan struct{}
+	FooInput  chan struct {
+		Arg0 int
+		Arg1 string
+	}
+	FooOutput chan struct {
+		Err error
+	}
+}
+
+func newFakeRecordMock() *fakeRecordMock {
+	m := &fakeRecordMock{}
+	m.FooCalled = make(chan struct{}, 100)
+	m.FooInput = make(chan struct {
+		Arg0 int
+		Arg1 string
+	}, 100)
+	m.FooOutput = make(chan struct {
+		Err error
+	}, 100)
+	return m
+}
+
+func (m *fakeRecordMock) Foo(arg0 int, arg1 string) error {
+	m.FooCalled <- struct{}{}
+	m.FooInput <- struct {
+		Arg0 int
+		Arg1 string
+	}{arg0, arg1}
+	out := <-m.FooOutput
+	return out.Err
+}
+
 func TestHaveMethodExecuted(t *testing.T) {
 	o := onpar.New()
 	defer o.Run(t)
@@ -286,6 +339,44 @@ func TestHaveMethodExecuted(t *testing.T) {
 		_, err := m.Match(fm)
 		expect(err).To(not(haveOccurred()))
 	})
+
+	o.Spec("it finds fields by their hel tags", func(t *testing.T, expect expectation) {
+		fm := newFakeTaggedMock()
+
+		m := pers.HaveMethodExecuted("Foo", pers.WithArgs(12))
+		_, err := m.Match(fm)
+		expect(err).To(haveOccurred())
+
+		fm.Foo(12)
+		_, err = m.Match(fm)
+		expect(err).To(not(haveOccurred()))
+
+		fm.Foo(13)
+		_, err = m.Match(fm)
+		expect(err).To(haveOccurred())
+	})
+
+	o.Spec("it receives arguments from record channels", func(t *testing.T, expect expectation) {
+		fm := newFakeRecordMock()
+		pers.Return(fm.FooOutput, nil)
+		pers.Return(fm.FooOutput, nil)
+		fm.Foo(12, "foo")
+		fm.Foo(13, "bar")
+
+		var (
+			arg0 int
+			arg1 string
+		)
+		m := pers.HaveMethodExecuted("Foo", pers.WithArgs(12, "foo"), pers.StoreArgs(&arg0, &arg1))
+		_, err := m.Match(fm)
+		expect(err).To(not(haveOccurred()))
+		expect(arg0).To(equal(12))
+		expect(arg1).To(equal("foo"))
+
+		m = pers.HaveMethodExecuted("Foo", pers.WithArgs(12, "foo"))
+		_, err = m.Match(fm)
+		expect(err).To(haveOccurred())
+	})
 }
 
 func ExampleStoreArgs() {
@@ -5,4 +5,7 @@
 // Package pers (hel/pers ... get it?) contains a bunch of helpers
 // for working with hel mocks.  From making a mock consistently
 // return to matchers - they'll all be here.
+//
+// pers works with runtime style mocks without importing helrt, so it
+// doesn't require Go 1.18 (although runtime style mocks do).
 package pers
@@ -0,0 +1,14 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package pers
+
+import "github.com/nelsam/hel/helrt"
+
+// helrt.Queue must stay in sync with callQueue, or pers would stop
+// recognizing runtime style mocks.
+var _ callQueue = helrt.Queue(nil)
@@ -7,8 +7,14 @@ package pers
 // Return will add a given value to the channel or struct of channels.
 // This isn't very useful with a single value, so it's intended more
 // to support structs full of channels, such as the ones that hel
-// generates for return values in its mocks.
+// generates for return values in its mocks.  Channels of record
+// structs (generated with --records) are sent a single record built
+// from args.  The call queues of runtime style mocks (e.g. FooCall)
+// queue one set of results built from args.
 func Return(mock interface{}, args ...interface{}) error {
+	if q, ok := queue(mock); ok {
+		return q.ReturnValues(args...)
+	}
 	cases, err := selectCases(mock, args...)
 	if err != nil {
 		return err
@@ -102,4 +102,31 @@ func TestReturn(t *testing.T) {
 		expect(errs).To(chain(receive(wait), not(haveOccurred())))
 	})
 
+	o.Spec("it returns a record on a channel of structs", func(expect expectation) {
+		type fooReturns struct {
+			Foo string
+			Err error
+		}
+		c := make(chan fooReturns)
+		errs := make(chan error)
+		go func() {
+			errs <- pers.Return(c, "foo", nil)
+		}()
+		expect(c).To(chain(receive(wait), equal(fooReturns{Foo: "foo"})))
+		expect(errs).To(chain(receive(wait), not(haveOccurred())))
+
+		go func() {
+			errs <- pers.Return(c, fooReturns{Foo: "bar"})
+		}()
+		expect(c).To(chain(receive(wait), equal(fooReturns{Foo: "bar"})))
+		expect(errs).To(chain(receive(wait), not(haveOccurred())))
+
+		err := pers.Return(c, "foo")
+		expect(err).To(haveOccurred())
+		expect(err.Error()).To(containSubstring("expected 2 arguments"))
+
+		err = pers.Return(c, 1, nil)
+		expect(err).To(haveOccurred())
+		expect(err.Error()).To(containSubstring("not assignable"))
+	})
 }
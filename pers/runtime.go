@@ -0,0 +1,56 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package pers
+
+import (
+	"reflect"
+	"time"
+)
+
+// callQueue is the part of helrt.Queue that pers uses.  pers doesn't
+// import helrt, which uses generics, so that pers still builds on
+// versions of Go before 1.18; every helrt.Call implements callQueue
+// all the same.
+type callQueue interface {
+	Method() string
+	ReceiveArgs(timeout time.Duration) (args []reflect.Value, ok bool)
+	ReturnValues(values ...interface{}) error
+	AlwaysReturnValues(values ...interface{}) (stop func(), err error)
+}
+
+// queue returns v as a callQueue, if it is a (pointer to a)
+// helrt.Call.  Calls only hold channels, so a copy of one (e.g. when
+// a test passes mock.FooCall rather than &mock.FooCall) shares its
+// queues.
+func queue(v interface{}) (callQueue, bool) {
+	if q, ok := v.(callQueue); ok {
+		return q, true
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil, false
+	}
+	p := reflect.New(rv.Type())
+	p.Elem().Set(rv)
+	q, ok := p.Interface().(callQueue)
+	return q, ok
+}
+
+// methodQueue returns the callQueue for the method named method on the
+// mock struct v, if v is a runtime style mock.  The queue is found by
+// the method name that it was initialized with, rather than by the
+// name of its field.
+func methodQueue(v reflect.Value, method string) (callQueue, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !f.CanInterface() {
+			continue
+		}
+		if q, ok := queue(f.Interface()); ok && q.Method() == method {
+			return q, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,118 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package pers_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nelsam/hel/helrt"
+	"github.com/nelsam/hel/pers"
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+)
+
+type fakeRuntimeMock struct {
+	// The call queues are found by their method names, so they are
+	// named unlike hel's default fields.
+	Q1 helrt.Call[struct {
+		Arg0 int
+		Arg1 string
+	}, struct {
+		Err error
+	}]
+	Q2 helrt.Call[struct{}, struct{}]
+}
+
+func newFakeRuntimeMock() *fakeRuntimeMock {
+	m := &fakeRuntimeMock{}
+	m.Q1.Init("Foo", 100, 100)
+	m.Q2.Init("Bar", 100, 100)
+	return m
+}
+
+func (m *fakeRuntimeMock) Foo(arg0 int, arg1 string) error {
+	out := m.Q1.Invoke(struct {
+		Arg0 int
+		Arg1 string
+	}{arg0, arg1})
+	return out.Err
+}
+
+func (m *fakeRuntimeMock) Bar() {
+	m.Q2.Record(struct{}{})
+}
+
+func TestRuntimeMocks(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expectation {
+		return expect.New(t)
+	})
+
+	o.Spec("it matches calls by their call queues", func(expect expectation) {
+		fm := newFakeRuntimeMock()
+		pers.Return(fm.Q1, nil)
+		pers.Return(fm.Q1, nil)
+		fm.Foo(12, "foo")
+		fm.Foo(13, "bar")
+		fm.Bar()
+
+		var (
+			arg0 int
+			arg1 string
+		)
+		m := pers.HaveMethodExecuted("Foo", pers.WithArgs(12, "foo"), pers.StoreArgs(&arg0, &arg1))
+		_, err := m.Match(fm)
+		expect(err).To(not(haveOccurred()))
+		expect(arg0).To(equal(12))
+		expect(arg1).To(equal("foo"))
+
+		m = pers.HaveMethodExecuted("Foo", pers.WithArgs(12, "foo"))
+		_, err = m.Match(fm)
+		expect(err).To(haveOccurred())
+
+		_, err = pers.HaveMethodExecuted("Bar").Match(fm)
+		expect(err).To(not(haveOccurred()))
+	})
+
+	o.Spec("it fails when the method has _not_ been called", func(expect expectation) {
+		m := pers.HaveMethodExecuted("Foo", pers.Within(10*time.Millisecond))
+		_, err := m.Match(newFakeRuntimeMock())
+		expect(err).To(haveOccurred())
+		expect(err.Error()).To(equal("pers: expected method Foo to have been called, but it was not"))
+	})
+
+	o.Spec("it returns values on call queues", func(expect expectation) {
+		fm := newFakeRuntimeMock()
+		err := pers.Return(&fm.Q1, errors.New("foo"))
+		expect(err).To(not(haveOccurred()))
+		expect(fm.Foo(1, "")).To(haveOccurred())
+
+		err = pers.Return(fm.Q1, "foo")
+		expect(err).To(haveOccurred())
+		expect(err.Error()).To(containSubstring("not assignable"))
+
+		err = pers.Return(fm.Q1)
+		expect(err).To(haveOccurred())
+		expect(err.Error()).To(containSubstring("expected 1 value"))
+	})
+
+	o.Spec("it consistently returns values on call queues", func(expect expectation) {
+		fm := newFakeRuntimeMock()
+		done, err := pers.ConsistentlyReturn(fm.Q1, errors.New("foo"))
+		expect(err).To(not(haveOccurred()))
+		for i := 0; i < 200; i++ {
+			expect(fm.Foo(i, "")).To(haveOccurred())
+			fm.Q1.Args()
+		}
+		done()
+	})
+}
@@ -0,0 +1,40 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package pers
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// SideEffect sets fn as the side effect of the method named method on
+// mock, which must be a pointer to a mock generated with
+// --side-effects.  fn is called with the method's arguments each time
+// the method is called, before the method receives its return values,
+// so it may modify them (e.g. fill the buffer passed to Read).  A nil
+// fn removes the side effect.
+//
+// SideEffect is not synchronized with calls to the mock, so it should
+// be called before the mock is used.
+func SideEffect(mock interface{}, method string, fn interface{}) error {
+	v := reflect.ValueOf(mock)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("pers: expected a pointer to a mock; got %T", mock)
+	}
+	field := methodField(v.Elem(), method, "sideEffect")
+	if !field.IsValid() || field.Kind() != reflect.Func {
+		return fmt.Errorf("pers: could not find a side effect for method '%s' on type %T (was it generated with --side-effects?)", method, mock)
+	}
+	if fn == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	fnV := reflect.ValueOf(fn)
+	if !fnV.Type().AssignableTo(field.Type()) {
+		return fmt.Errorf("pers: side effect for method '%s' must be a %s; got %T", method, field.Type(), fn)
+	}
+	field.Set(fnV)
+	return nil
+}
@@ -0,0 +1,105 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package pers_test
+
+import (
+	"testing"
+
+	"github.com/nelsam/hel/pers"
+	"github.com/poy/onpar"
+	"github.com/poy/onpar/expect"
+)
+
+type fakeReaderMock struct {
+	ReadCalled chan struct{}
+	ReadInput  struct {
+		P chan []byte
+	}
+	ReadOutput struct {
+		N   chan int
+		Err chan error
+	}
+	ReadSideEffect func(p []byte)
+}
+
+func newFakeReaderMock() *fakeReaderMock {
+	m := &fakeReaderMock{}
+	m.ReadCalled = make(chan struct{}, 100)
+	m.ReadInput.P = make(chan []byte, 100)
+	m.ReadOutput.N = make(chan int, 100)
+	m.ReadOutput.Err = make(chan error, 100)
+	return m
+}
+
+func (m *fakeReaderMock) Read(p []byte) (int, error) {
+	m.ReadCalled <- struct{}{}
+	m.ReadInput.P <- p
+	if m.ReadSideEffect != nil {
+		m.ReadSideEffect(p)
+	}
+	return <-m.ReadOutput.N, <-m.ReadOutput.Err
+}
+
+type fakeTaggedSideEffectMock struct {
+	FooCalled chan struct{}
+	FooHook   func(int) `hel:"Foo,sideEffect"`
+}
+
+func TestSideEffect(t *testing.T) {
+	o := onpar.New()
+	defer o.Run(t)
+
+	o.BeforeEach(func(t *testing.T) expectation {
+		return expect.New(t)
+	})
+
+	o.Spec("it runs the side effect during the call", func(expect expectation) {
+		m := newFakeReaderMock()
+		err := pers.SideEffect(m, "Read", func(p []byte) {
+			copy(p, "foo")
+		})
+		expect(err).To(not(haveOccurred()))
+
+		pers.Return(m.ReadOutput, 3, nil)
+		buf := make([]byte, 3)
+		n, err := m.Read(buf)
+		expect(err).To(not(haveOccurred()))
+		expect(n).To(equal(3))
+		expect(string(buf)).To(equal("foo"))
+	})
+
+	o.Spec("it removes the side effect when fn is nil", func(expect expectation) {
+		m := newFakeReaderMock()
+		m.ReadSideEffect = func([]byte) {}
+		err := pers.SideEffect(m, "Read", nil)
+		expect(err).To(not(haveOccurred()))
+		expect(m.ReadSideEffect == nil).To(equal(true))
+	})
+
+	o.Spec("it finds side effects by their hel tags", func(expect expectation) {
+		m := &fakeTaggedSideEffectMock{}
+		err := pers.SideEffect(m, "Foo", func(int) {})
+		expect(err).To(not(haveOccurred()))
+		expect(m.FooHook == nil).To(equal(false))
+	})
+
+	o.Spec("it errors if the mock has no side effect for the method", func(expect expectation) {
+		err := pers.SideEffect(newFakeMock(), "Foo", func(int, string) {})
+		expect(err).To(haveOccurred())
+		expect(err.Error()).To(containSubstring("--side-effects"))
+	})
+
+	o.Spec("it errors if fn is the wrong type", func(expect expectation) {
+		err := pers.SideEffect(newFakeReaderMock(), "Read", func(string) {})
+		expect(err).To(haveOccurred())
+		expect(err.Error()).To(containSubstring("must be a func([]uint8)"))
+	})
+
+	o.Spec("it errors if mock is not a pointer", func(expect expectation) {
+		err := pers.SideEffect(*newFakeReaderMock(), "Read", func([]byte) {})
+		expect(err).To(haveOccurred())
+		expect(err.Error()).To(containSubstring("expected a pointer to a mock"))
+	})
+}
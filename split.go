@@ -0,0 +1,153 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/nelsam/hel/mocks"
+)
+
+// fileNameData is the data that file name templates are executed
+// with.
+type fileNameData struct {
+	// Interface is the name of the mocked interface.  Dependency mocks
+	// may have their package name prepended to keep them distinct
+	// from other mocks (e.g. BFoo for b.Foo).
+	Interface string
+
+	// Package is the name of the package that the mocked interface is
+	// declared in.
+	Package string
+}
+
+// parseFileName parses text as a file name template.
+func parseFileName(text string) (*template.Template, error) {
+	return template.New("split-output").Funcs(mocks.NameFuncs).Parse(text)
+}
+
+// fileName executes tmpl to name the file that an interface's mock
+// will be written to.
+func fileName(tmpl *template.Template, data fileNameData) (string, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	name := b.String()
+	if name == "" || strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') {
+		return "", fmt.Errorf("split output template produced invalid file name %q for %s", name, data.Interface)
+	}
+	return name, nil
+}
+
+// outputFile is a file that mocks will be written to.
+type outputFile struct {
+	name  string
+	mocks mocks.Mocks
+}
+
+// splitMocks groups m by the file that each mock should be written
+// to.  Without a split template, all mocks are written to
+// opts.outputName.  Files are returned in the order that their first
+// mock appears in m, so the result is deterministic.
+func splitMocks(m mocks.Mocks, localPkg string, opts genOptions) ([]outputFile, error) {
+	if opts.split == nil {
+		return []outputFile{{name: opts.outputName, mocks: m}}, nil
+	}
+	var files []outputFile
+	index := make(map[string]int)
+	for _, mock := range m {
+		pkg := localPkg
+		if i := strings.LastIndex(mock.Interface(), "."); i >= 0 {
+			pkg = mock.Interface()[:i]
+		}
+		name, err := fileName(opts.split, fileNameData{Interface: mock.BaseName(), Package: pkg})
+		if err != nil {
+			return nil, err
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(files)
+			index[name] = i
+			files = append(files, outputFile{name: name})
+		}
+		files[i].mocks = append(files[i].mocks, mock)
+	}
+	return files, nil
+}
+
+// outputLayout returns the layout of the files that split output is
+// written to: the file name template followed by the type patterns
+// that select the mocked types.  It is recorded in each file's header
+// so that files from the same layout can be found later, regardless of
+// how the options were spelled or where they were set.
+func outputLayout(opts genOptions) []string {
+	patterns := append([]string(nil), opts.typePatterns...)
+	sort.Strings(patterns)
+	layout := []string{opts.split.Tree.Root.String()}
+	for i, pattern := range patterns {
+		if i > 0 && pattern == patterns[i-1] {
+			continue
+		}
+		layout = append(layout, pattern)
+	}
+	return layout
+}
+
+// staleFiles returns the files in dir that hel generated with layout
+// but which are not in keep.  These are the files of interfaces which
+// no longer exist (or are no longer selected) when output is split.
+// An error is returned for files which have been edited, unless force
+// is true.
+func staleFiles(dir string, layout []string, keep map[string]bool, force bool) (stale []string, err error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range matches {
+		if keep[filepath.Base(path)] {
+			continue
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		h, generated, err := mocks.ReadHeader(bytes.NewReader(src))
+		if err != nil {
+			return nil, err
+		}
+		if !generated || !reflect.DeepEqual(h.Layout, layout) {
+			continue
+		}
+		intact, err := mocks.Intact(src)
+		if err != nil {
+			return nil, err
+		}
+		if !intact && !force {
+			return nil, fmt.Errorf("refusing to remove stale file %s: it has been edited since hel generated it (use --force to remove it anyway)", path)
+		}
+		stale = append(stale, path)
+	}
+	return stale, nil
+}
+
+// pruneStale removes the stale files at paths.
+func pruneStale(paths []string) (removed []string, err error) {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
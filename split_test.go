@@ -0,0 +1,195 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/a8m/expect"
+)
+
+func TestFileName(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		tmpl     string
+		data     fileNameData
+		expected string
+		err      bool
+	}{
+		{
+			name:     "interface",
+			tmpl:     "mock_{{.Interface}}_test.go",
+			data:     fileNameData{Interface: "Foo", Package: "foo"},
+			expected: "mock_Foo_test.go",
+		},
+		{
+			name:     "snake",
+			tmpl:     "{{.Package}}_{{snake .Interface}}_test.go",
+			data:     fileNameData{Interface: "HTTPClient", Package: "foo"},
+			expected: "foo_http_client_test.go",
+		},
+		{
+			name:     "lower",
+			tmpl:     "{{lower .Interface}}_test.go",
+			data:     fileNameData{Interface: "FooBar", Package: "foo"},
+			expected: "foobar_test.go",
+		},
+		{
+			name:     "title",
+			tmpl:     "mock{{title .Package}}{{.Interface}}_test.go",
+			data:     fileNameData{Interface: "Foo", Package: "bar"},
+			expected: "mockBarFoo_test.go",
+		},
+		{
+			name: "empty",
+			tmpl: "{{if false}}foo{{end}}",
+			data: fileNameData{Interface: "Foo", Package: "foo"},
+			err:  true,
+		},
+		{
+			name: "directories",
+			tmpl: "mocks/{{.Interface}}_test.go",
+			data: fileNameData{Interface: "Foo", Package: "foo"},
+			err:  true,
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			expect := expect.New(t)
+			tmpl, err := parseFileName(test.tmpl)
+			expect(err).To.Be.Nil().Else.FailNow()
+			name, err := fileName(tmpl, test.data)
+			expect(err != nil).To.Equal(test.err)
+			expect(name).To.Equal(test.expected)
+		})
+	}
+}
+
+func TestOutputLayout(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		split    string
+		patterns []string
+		expected []string
+	}{
+		{
+			name:     "template only",
+			split:    "mock_{{.Interface}}_test.go",
+			expected: []string{"mock_{{.Interface}}_test.go"},
+		},
+		{
+			name:     "spacing in actions",
+			split:    "mock_{{ .Interface }}_test.go",
+			expected: []string{"mock_{{.Interface}}_test.go"},
+		},
+		{
+			name:     "type patterns are sorted and deduplicated",
+			split:    "{{.Interface}}_test.go",
+			patterns: []string{"Foo", "Bar", "Foo"},
+			expected: []string{"{{.Interface}}_test.go", "Bar", "Foo"},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			expect := expect.New(t)
+			split, err := splitTemplate(test.split)
+			expect(err).To.Be.Nil().Else.FailNow()
+			layout := outputLayout(genOptions{split: split, typePatterns: test.patterns})
+			expect(layout).To.Equal(test.expected)
+		})
+	}
+}
+
+func TestStaleFiles(t *testing.T) {
+	layout := []string{"{{.Interface}}_test.go"}
+	for _, test := range []struct {
+		name       string
+		force      bool
+		files      map[string]string
+		fileLayout []string
+		keep       []string
+		expected   []string
+		err        bool
+	}{
+		{
+			name:       "files that are not kept are stale",
+			files:      map[string]string{"Foo_test.go": generatedFile, "Bar_test.go": generatedFile},
+			fileLayout: layout,
+			keep:       []string{"Foo_test.go"},
+			expected:   []string{"Bar_test.go"},
+		},
+		{
+			name:       "kept files are not stale",
+			files:      map[string]string{"Foo_test.go": generatedFile, "Bar_test.go": generatedFile},
+			fileLayout: layout,
+			keep:       []string{"Foo_test.go", "Bar_test.go"},
+		},
+		{
+			name:       "files from other layouts are not stale",
+			files:      map[string]string{"Bar_test.go": generatedFile},
+			fileLayout: []string{"mock_{{.Interface}}_test.go"},
+		},
+		{
+			name:       "files without a layout are not stale",
+			files:      map[string]string{"Bar_test.go": generatedFile},
+			fileLayout: nil,
+		},
+		{
+			name:       "source files are not stale",
+			files:      map[string]string{"bar.go": sourceFile},
+			fileLayout: layout,
+		},
+		{
+			name:       "edited files are an error",
+			files:      map[string]string{"Bar_test.go": editedFile},
+			fileLayout: layout,
+			err:        true,
+		},
+		{
+			name:       "force allows edited files",
+			force:      true,
+			files:      map[string]string{"Bar_test.go": editedFile},
+			fileLayout: layout,
+			expected:   []string{"Bar_test.go"},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			expect := expect.New(t)
+			var directives []string
+			if test.fileLayout != nil {
+				directives = append(directives, "layout "+test.fileLayout[0])
+			}
+			dir, cleanup := tempDir(t, testFiles(expect, test.files, directives...))
+			defer cleanup()
+
+			keep := make(map[string]bool, len(test.keep))
+			for _, name := range test.keep {
+				keep[name] = true
+			}
+			stale, err := staleFiles(dir, layout, keep, test.force)
+			if test.err {
+				expect(err).Not.To.Be.Nil()
+				return
+			}
+			expect(err).To.Be.Nil().Else.FailNow()
+			var names []string
+			for _, path := range stale {
+				names = append(names, filepath.Base(path))
+			}
+			expect(names).To.Equal(test.expected)
+
+			removed, err := pruneStale(stale)
+			expect(err).To.Be.Nil().Else.FailNow()
+			expect(removed).To.Equal(stale)
+			for _, path := range removed {
+				_, err := os.Stat(path)
+				expect(os.IsNotExist(err)).To.Be.Ok()
+			}
+		})
+	}
+}
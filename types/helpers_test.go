@@ -13,7 +13,7 @@ import (
 const packagePrefix = "package foo\n\n"
 
 func parse(expect expectation, code string) *ast.File {
-	f, err := parser.ParseFile(token.NewFileSet(), "", packagePrefix+code, 0)
+	f, err := parser.ParseFile(token.NewFileSet(), "", packagePrefix+code, parser.ParseComments)
 	expect(err).To(not(haveOccurred()))
 	return f
 }
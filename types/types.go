@@ -7,6 +7,7 @@ package types
 import (
 	"fmt"
 	"go/ast"
+	"go/token"
 	"log"
 	"regexp"
 	"strings"
@@ -270,6 +271,7 @@ func loadPkgTypeSpecs(pkg *packages.Package, dir GoDir) (specs []*ast.TypeSpec,
 }
 
 func loadFileTypeSpecs(f *ast.File) (specs []*ast.TypeSpec) {
+	attachDocs(f)
 	for _, obj := range f.Scope.Objects {
 		spec, ok := obj.Decl.(*ast.TypeSpec)
 		if !ok {
@@ -283,6 +285,23 @@ func loadFileTypeSpecs(f *ast.File) (specs []*ast.TypeSpec) {
 	return specs
 }
 
+// attachDocs copies the doc comments of ungrouped type declarations
+// (e.g. type Foo interface{}), which the parser attaches to the
+// *ast.GenDecl, to their *ast.TypeSpec.
+func attachDocs(f *ast.File) {
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE || gen.Lparen.IsValid() {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			if spec, ok := spec.(*ast.TypeSpec); ok && spec.Doc == nil {
+				spec.Doc = gen.Doc
+			}
+		}
+	}
+}
+
 func flattenAnon(specs, withSpecs []*ast.TypeSpec, withImports []*ast.ImportSpec, dir GoDir) {
 	for _, spec := range specs {
 		inter := spec.Type.(*ast.InterfaceType)
@@ -92,6 +92,35 @@ func TestTypes(t *testing.T) {
 		expectNamesToMatch(expect, fooContainers[0].ExportedTypes(), "Foo", "FooBar", "BarFoo")
 	})
 
+	o.Spec("Docs", func(expect expectation, mockGoDir *mockGoDir) {
+		pers.ConsistentlyReturn(mockGoDir.PathOutput, "/some/path")
+		pers.ConsistentlyReturn(mockGoDir.PackageOutput, &packages.Package{
+			Name: "foo",
+			Syntax: []*ast.File{
+				parse(expect, `
+    // Foo is documented.
+    type Foo interface {}
+
+    // Grouped types keep their own docs.
+    type (
+        // Bar is documented.
+        Bar interface {}
+    )
+    `),
+			},
+		})
+		found := types.Load(mockGoDir)
+		expect(found).To(haveLen(1))
+		specs := found[0].ExportedTypes()
+		expect(specs).To(haveLen(2))
+		docs := make(map[string]string)
+		for _, spec := range specs {
+			docs[spec.Name.String()] = spec.Doc.Text()
+		}
+		expect(docs["Foo"]).To(equal("Foo is documented.\n"))
+		expect(docs["Bar"]).To(equal("Bar is documented.\n"))
+	})
+
 	o.Spec("LocalDependencies", func(expect expectation, mockGoDir *mockGoDir) {
 		pers.ConsistentlyReturn(mockGoDir.PathOutput, "/some/path")
 		pers.ConsistentlyReturn(mockGoDir.PackageOutput, &packages.Package{
@@ -0,0 +1,69 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/nelsam/hel/mocks"
+	"github.com/spf13/cobra"
+)
+
+func versionCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "version [file...]",
+		Short: "Print the version of hel",
+		Long: "version prints the version of hel, as recorded in the headers of the files that " +
+			"it generates, along with details about how hel was built.  For each file passed to it, " +
+			"it also prints the provenance recorded in the file's header: the //hel:version, " +
+			"//hel:args, //hel:layout, and //hel:types directives, along with the hash and checksum " +
+			"used to detect changes.",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Printf("hel %s\n", mocks.Version())
+			fmt.Printf("  go: %s\n", runtime.Version())
+			if info, ok := debug.ReadBuildInfo(); ok {
+				fmt.Printf("  module: %s\n", info.Main.Path)
+			}
+			failed := false
+			for _, path := range args {
+				directives, err := fileDirectives(path)
+				if err != nil {
+					fmt.Printf("Error: %s\n", err)
+					failed = true
+					continue
+				}
+				fmt.Printf("\n%s:\n", path)
+				for _, line := range strings.Split(strings.TrimSuffix(directives, "\n"), "\n") {
+					fmt.Printf("  %s\n", line)
+				}
+			}
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+// fileDirectives returns the directives in the header of the file at
+// path, which must have been generated by hel.
+func fileDirectives(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h, ok, err := mocks.ReadHeader(f)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("%s was not generated by hel", path)
+	}
+	return h.Directives(), nil
+}
@@ -0,0 +1,37 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/a8m/expect"
+	"github.com/nelsam/hel/mocks"
+)
+
+func TestFileDirectives(t *testing.T) {
+	expect := expect.New(t)
+
+	src := generatedSrc(expect, "args -t Foo")
+	dir, cleanup := tempDir(t, map[string][]byte{
+		"helheim_test.go": src,
+		"foo.go":          []byte("package foo\n"),
+	})
+	defer cleanup()
+
+	h, ok, err := mocks.ReadHeader(bytes.NewReader(src))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(ok).To.Be.Ok().Else.FailNow()
+	directives, err := fileDirectives(filepath.Join(dir, "helheim_test.go"))
+	expect(err).To.Be.Nil().Else.FailNow()
+	expect(directives).To.Equal("//hel:version (devel)\n//hel:args -t Foo\n//hel:types Foo\n//hel:hash 0\n//hel:sum " + h.Sum + "\n")
+
+	_, err = fileDirectives(filepath.Join(dir, "foo.go"))
+	expect(err).Not.To.Be.Nil()
+	_, err = fileDirectives(filepath.Join(dir, "missing.go"))
+	expect(err).Not.To.Be.Nil()
+}
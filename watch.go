@@ -0,0 +1,191 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nelsam/hel/mocks"
+	"github.com/nelsam/hel/packages"
+	"github.com/nelsam/hel/types"
+)
+
+// fileState is the information used to detect changes to a file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// sourceState maps the names of the non-generated go files in a
+// directory to their fileState.
+type sourceState map[string]fileState
+
+func (s sourceState) equal(other sourceState) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for name, state := range s {
+		otherState, ok := other[name]
+		if !ok || !state.modTime.Equal(otherState.modTime) || state.size != otherState.size {
+			return false
+		}
+	}
+	return true
+}
+
+// watchDirs polls dirs every interval, regenerating mocks for any
+// directory whose non-generated go files have changed.  Directories
+// whose paths are in failed are regenerated on the first poll, as are
+// directories whose last regeneration failed, until it succeeds.
+// Errors are reported (once, until they change) and then ignored, so
+// that watching continues.  It never returns.
+func watchDirs(dirs []packages.Dir, failed map[string]bool, opts genOptions, interval time.Duration) {
+	var watched []packages.Dir
+	for _, dir := range dirs {
+		if dir.Path() != "" {
+			watched = append(watched, dir)
+		}
+	}
+	states := make(map[string]sourceState, len(watched))
+	for _, dir := range watched {
+		if failed[dir.Path()] {
+			continue
+		}
+		state, err := readSourceState(dir.Path(), opts.outputName)
+		if err != nil {
+			report(dir, "", err)
+			continue
+		}
+		states[dir.Path()] = state
+	}
+	fmt.Printf("Watching %d %s for changes (ctrl-c to exit)\n", len(watched), pluralize(watched, "directory", "directories"))
+	errs := make(map[string]string)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for _, dir := range watched {
+			state, err := readSourceState(dir.Path(), opts.outputName)
+			if err == nil {
+				if last, ok := states[dir.Path()]; ok && state.equal(last) {
+					continue
+				}
+				var result string
+				result, err = regenerate(dir, opts)
+				if err == nil {
+					// The state is only stored once the mocks match it,
+					// so that failures are retried.
+					states[dir.Path()] = state
+					delete(errs, dir.Path())
+					report(dir, result, nil)
+					continue
+				}
+			}
+			if errs[dir.Path()] == err.Error() {
+				continue
+			}
+			errs[dir.Path()] = err.Error()
+			report(dir, "", err)
+		}
+	}
+}
+
+// regenerate reloads dir and regenerates its mocks, returning a short
+// description of the result.
+func regenerate(dir packages.Dir, opts genOptions) (result string, err error) {
+	defer func() {
+		// Loading types still panics on some errors, which shouldn't
+		// stop us from watching.
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	reloaded := packages.Load(dir.Package().PkgPath)
+	if len(reloaded) != 1 {
+		return "", fmt.Errorf("expected to load 1 package; loaded %d", len(reloaded))
+	}
+	if errs := reloaded[0].Package().Errors; len(errs) > 0 {
+		return "", errs[0]
+	}
+	typeDirs := types.Load(reloaded[0]).Filter(opts.typePatterns...)
+	if len(typeDirs) == 0 {
+		return "no matching interfaces", nil
+	}
+	paths, err := writeMocks(typeDirs[0], opts)
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "up to date", nil
+	}
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		names = append(names, filepath.Base(path))
+	}
+	return "wrote " + strings.Join(names, ", "), nil
+}
+
+func report(dir packages.Dir, result string, err error) {
+	path := dir.Path()
+	if rel, relErr := filepath.Rel(cwd(), path); relErr == nil && !strings.HasPrefix(rel, "..") {
+		path = "./" + filepath.ToSlash(rel)
+	}
+	stamp := time.Now().Format("15:04:05")
+	if err != nil {
+		fmt.Printf("%s %s: error: %s\n", stamp, path, err)
+		return
+	}
+	fmt.Printf("%s %s: %s\n", stamp, path, result)
+}
+
+// readSourceState reads the state of the go files in dir, skipping
+// outputName and any other files generated by hel.
+func readSourceState(dir, outputName string) (sourceState, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	state := make(sourceState, len(matches))
+	for _, path := range matches {
+		if filepath.Base(path) == outputName {
+			continue
+		}
+		generated, err := isGenerated(path)
+		if err != nil {
+			return nil, err
+		}
+		if generated {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		state[filepath.Base(path)] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+	return state, nil
+}
+
+// isGenerated reports whether the file at path was generated by hel.
+func isGenerated(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	_, ok, err := mocks.ReadHeader(f)
+	return ok, err
+}
+
+func cwd() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	return wd
+}
@@ -0,0 +1,118 @@
+// This is free and unencumbered software released into the public
+// domain.  For more information, see <http://unlicense.org> or the
+// accompanying UNLICENSE file.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/a8m/expect"
+)
+
+func TestReadSourceState(t *testing.T) {
+	expect := expect.New(t)
+	files := map[string]string{
+		"foo.go":           sourceFile,
+		"bar.go":           sourceFile,
+		"helheim_test.go":  sourceFile,
+		"mock_foo_test.go": generatedFile,
+	}
+	dir, cleanup := tempDir(t, testFiles(expect, files))
+	defer cleanup()
+
+	state, err := readSourceState(dir, "helheim_test.go")
+	expect(err).To.Be.Nil().Else.FailNow()
+	var names []string
+	for name := range state {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	expect(names).To.Equal([]string{"bar.go", "foo.go"})
+}
+
+func TestReadSourceState_Changes(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		change  func(expect func(interface{}) *expect.Expect, dir string)
+		changed bool
+	}{
+		{
+			name:   "no changes",
+			change: func(func(interface{}) *expect.Expect, string) {},
+		},
+		{
+			name: "size",
+			change: func(expect func(interface{}) *expect.Expect, dir string) {
+				path := filepath.Join(dir, "foo.go")
+				info, err := os.Stat(path)
+				expect(err).To.Be.Nil().Else.FailNow()
+				err = ioutil.WriteFile(path, []byte("package foo\n\nvar foo int\n"), 0644)
+				expect(err).To.Be.Nil().Else.FailNow()
+				// Keep the mod time, so that only the size changes.
+				err = os.Chtimes(path, info.ModTime(), info.ModTime())
+				expect(err).To.Be.Nil().Else.FailNow()
+			},
+			changed: true,
+		},
+		{
+			name: "mod time",
+			change: func(expect func(interface{}) *expect.Expect, dir string) {
+				later := time.Now().Add(time.Hour)
+				err := os.Chtimes(filepath.Join(dir, "foo.go"), later, later)
+				expect(err).To.Be.Nil().Else.FailNow()
+			},
+			changed: true,
+		},
+		{
+			name: "added file",
+			change: func(expect func(interface{}) *expect.Expect, dir string) {
+				err := ioutil.WriteFile(filepath.Join(dir, "baz.go"), []byte("package foo\n"), 0644)
+				expect(err).To.Be.Nil().Else.FailNow()
+			},
+			changed: true,
+		},
+		{
+			name: "removed file",
+			change: func(expect func(interface{}) *expect.Expect, dir string) {
+				err := os.Remove(filepath.Join(dir, "foo.go"))
+				expect(err).To.Be.Nil().Else.FailNow()
+			},
+			changed: true,
+		},
+		{
+			name: "output",
+			change: func(expect func(interface{}) *expect.Expect, dir string) {
+				err := ioutil.WriteFile(filepath.Join(dir, "helheim_test.go"), []byte("package foo\n\nvar foo int\n"), 0644)
+				expect(err).To.Be.Nil().Else.FailNow()
+			},
+		},
+		{
+			name: "generated file",
+			change: func(expect func(interface{}) *expect.Expect, dir string) {
+				err := ioutil.WriteFile(filepath.Join(dir, "mock_bar_test.go"), generatedSrc(expect), 0644)
+				expect(err).To.Be.Nil().Else.FailNow()
+			},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			expect := expect.New(t)
+			files := map[string]string{"foo.go": sourceFile, "helheim_test.go": generatedFile}
+			dir, cleanup := tempDir(t, testFiles(expect, files))
+			defer cleanup()
+
+			before, err := readSourceState(dir, "helheim_test.go")
+			expect(err).To.Be.Nil().Else.FailNow()
+			test.change(expect, dir)
+			after, err := readSourceState(dir, "helheim_test.go")
+			expect(err).To.Be.Nil().Else.FailNow()
+			expect(before.equal(after)).To.Equal(!test.changed)
+		})
+	}
+}